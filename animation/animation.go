@@ -7,26 +7,169 @@ import (
 	"github.com/yuricorredor/platformer/types"
 )
 
+// Mode is the order a clip shows its images in.
+type Mode int
+
+const (
+	Forward Mode = iota
+	Reverse
+	PingPong
+	// ReversePingPong is PingPong starting from the last image.
+	ReversePingPong
+)
+
+// Animation is a self-contained clip plus its playback state, for things like
+// particles that do not need an Animator. Durations, when set, gives each
+// image its own duration and falls back to ImageDuration for missing entries.
+// A zero Speed plays at normal speed and a negative one holds the current
+// frame.
 type Animation struct {
 	Images        []*ebiten.Image
 	ImageDuration int
+	Durations     []int
+	Mode          Mode
+	Speed         float64
 	Loop          bool
 	Done          bool
 	Frame         int
 	Offset        types.Vector
+	remainder     float64
+}
+
+func (a *Animation) sequence() sequence {
+	return sequence{
+		images:        len(a.Images),
+		imageDuration: a.ImageDuration,
+		durations:     a.Durations,
+		mode:          a.Mode,
+	}
 }
 
 func (a *Animation) Update() {
-	if a.Loop {
-		a.Frame = (a.Frame + 1) % (len(a.Images) * a.ImageDuration)
-	} else {
-		a.Frame = int(math.Min(float64(a.Frame+1), float64((len(a.Images)-1)*a.ImageDuration)))
-		if a.Frame >= a.ImageDuration*(len(a.Images)-1) {
-			a.Done = true
+	a.Frame, a.remainder, a.Done = advance(a.Frame, a.remainder, a.Speed, a.sequence().total(), a.Loop)
+}
+
+func (a *Animation) Image() *ebiten.Image {
+	return a.Images[a.ImageIndex()]
+}
+
+func (a *Animation) ImageIndex() int {
+	return a.sequence().imageAt(a.Frame)
+}
+
+func (a *Animation) Reset() {
+	a.Frame = 0
+	a.Done = false
+	a.remainder = 0
+}
+
+// Seek jumps to the first time the given image is shown.
+func (a *Animation) Seek(image int) {
+	a.Reset()
+	a.Frame = a.sequence().startOf(image)
+}
+
+// sequence describes the order images are shown in during one pass of a clip,
+// and for how long each of them stays on screen.
+type sequence struct {
+	images        int
+	imageDuration int
+	durations     []int
+	mode          Mode
+}
+
+func (s sequence) length() int {
+	if (s.mode == PingPong || s.mode == ReversePingPong) && s.images > 1 {
+		return s.images*2 - 2
+	}
+
+	return s.images
+}
+
+func (s sequence) at(i int) int {
+	switch s.mode {
+	case Reverse:
+		return s.images - 1 - i
+	case PingPong:
+		if i >= s.images {
+			return s.images*2 - 2 - i
+		}
+	case ReversePingPong:
+		if i >= s.images {
+			return i - s.images + 1
 		}
+		return s.images - 1 - i
 	}
+
+	return i
 }
 
-func (a *Animation) Image() *ebiten.Image {
-	return a.Images[a.Frame/a.ImageDuration]
+func (s sequence) duration(image int) int {
+	if image < len(s.durations) && s.durations[image] > 0 {
+		return s.durations[image]
+	}
+
+	return int(math.Max(1, float64(s.imageDuration)))
+}
+
+func (s sequence) total() int {
+	total := 0
+	for i := 0; i < s.length(); i++ {
+		total += s.duration(s.at(i))
+	}
+
+	return total
+}
+
+func (s sequence) imageAt(frame int) int {
+	for i := 0; i < s.length(); i++ {
+		image := s.at(i)
+		frame -= s.duration(image)
+		if frame < 0 {
+			return image
+		}
+	}
+
+	return s.at(s.length() - 1)
+}
+
+func (s sequence) startOf(image int) int {
+	start := 0
+	for i := 0; i < s.length(); i++ {
+		if s.at(i) == image {
+			return start
+		}
+		start += s.duration(s.at(i))
+	}
+
+	return 0
+}
+
+func speedOrDefault(speed float64) float64 {
+	if speed == 0 {
+		return 1
+	}
+
+	return math.Max(0, speed)
+}
+
+// advance moves frame forward by speed ticks. Looping clips wrap around, the
+// others stop on their last image and are done once it has been shown for its
+// whole duration, even when the clip has a single image. A clip without images
+// stays on frame 0.
+func advance(frame int, remainder, speed float64, total int, loop bool) (int, float64, bool) {
+	if total == 0 {
+		return 0, 0, !loop
+	}
+
+	remainder += speedOrDefault(speed)
+	ticks := int(remainder)
+	remainder -= float64(ticks)
+
+	if loop {
+		return (frame + ticks) % total, remainder, false
+	}
+
+	frame = int(math.Min(float64(frame+ticks), float64(total)))
+	return frame, remainder, frame >= total
 }
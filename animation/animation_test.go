@@ -0,0 +1,56 @@
+package animation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestSequenceOrder(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		want []int
+	}{
+		{Forward, []int{0, 1, 2, 3}},
+		{Reverse, []int{3, 2, 1, 0}},
+		{PingPong, []int{0, 1, 2, 3, 2, 1}},
+		{ReversePingPong, []int{3, 2, 1, 0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		s := sequence{images: 4, imageDuration: 1, mode: test.mode}
+		got := []int{}
+		for i := 0; i < s.length(); i++ {
+			got = append(got, s.at(i))
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("mode %d: order %v, want %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestUpdateWithoutImages(t *testing.T) {
+	for _, loop := range []bool{true, false} {
+		a := &Animation{ImageDuration: 5, Loop: loop}
+		a.Update()
+
+		if a.Frame != 0 || a.Done == loop {
+			t.Errorf("loop %v: frame %d, done %v", loop, a.Frame, a.Done)
+		}
+	}
+}
+
+func TestNegativeSpeedHolds(t *testing.T) {
+	a := &Animation{ImageDuration: 5, Speed: -1, Loop: true}
+	a.Images = make([]*ebiten.Image, 3)
+	a.Frame = 4
+
+	for i := 0; i < 10; i++ {
+		a.Update()
+	}
+	if a.Frame != 4 {
+		t.Errorf("frame %d, want 4", a.Frame)
+	}
+}
@@ -0,0 +1,91 @@
+package animation
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/yuricorredor/platformer/types"
+)
+
+// Clip is the immutable description of an animation. Clips are meant to be
+// shared, all playback state lives in the Animator using them.
+type Clip struct {
+	Images        []*ebiten.Image
+	ImageDuration int
+	Durations     []int
+	Mode          Mode
+	Loop          bool
+	Offset        types.Vector
+}
+
+func (c *Clip) sequence() sequence {
+	return sequence{
+		images:        len(c.Images),
+		imageDuration: c.ImageDuration,
+		durations:     c.Durations,
+		mode:          c.Mode,
+	}
+}
+
+type Animator struct {
+	Clips     map[string]*Clip
+	Current   string
+	Frame     int
+	Done      bool
+	Speed     float64
+	remainder float64
+	// ticks is how many frames the last Update moved forward, before
+	// wrapping or clamping.
+	ticks int
+}
+
+func NewAnimator(clips map[string]*Clip, current string) *Animator {
+	return &Animator{
+		Clips:   clips,
+		Current: current,
+	}
+}
+
+func (a *Animator) Clip() *Clip {
+	return a.Clips[a.Current]
+}
+
+// Play switches to the named clip. Switching always starts the clip over, so
+// non-looping clips such as a jump play again every time they are entered.
+func (a *Animator) Play(name string) {
+	if name == a.Current {
+		return
+	}
+
+	a.Current = name
+	a.Reset()
+}
+
+func (a *Animator) Reset() {
+	a.Frame = 0
+	a.Done = false
+	a.remainder = 0
+}
+
+// Seek jumps to the first time the current clip shows the given image.
+func (a *Animator) Seek(image int) {
+	a.Reset()
+	a.Frame = a.Clip().sequence().startOf(image)
+}
+
+func (a *Animator) Update() {
+	clip := a.Clip()
+	before := a.remainder
+	a.Frame, a.remainder, a.Done = advance(a.Frame, a.remainder, a.Speed, clip.sequence().total(), clip.Loop)
+	a.ticks = int(before + speedOrDefault(a.Speed))
+}
+
+func (a *Animator) ImageIndex() int {
+	return a.Clip().sequence().imageAt(a.Frame)
+}
+
+func (a *Animator) Image() *ebiten.Image {
+	return a.Clip().Images[a.ImageIndex()]
+}
+
+func (a *Animator) Offset() types.Vector {
+	return a.Clip().Offset
+}
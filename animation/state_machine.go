@@ -0,0 +1,118 @@
+package animation
+
+// Transition moves the machine to To when Condition holds. An empty From
+// matches every state. When several transitions hold at once, the highest
+// Priority wins, and ties go to the one added first.
+type Transition[C any] struct {
+	From      string
+	To        string
+	Priority  int
+	Condition func(C) bool
+}
+
+// FrameEvent calls Callback when the clip of State reaches the image Frame.
+type FrameEvent[C any] struct {
+	State    string
+	Frame    int
+	Callback func(C)
+}
+
+// StateMachine picks the clip an Animator plays from declarative transitions.
+// C is whatever context the conditions and callbacks need to look at.
+type StateMachine[C any] struct {
+	Animator    *Animator
+	Transitions []Transition[C]
+	Events      []FrameEvent[C]
+	lastState   string
+	lastImage   int
+}
+
+func NewStateMachine[C any](animator *Animator) *StateMachine[C] {
+	return &StateMachine[C]{
+		Animator:  animator,
+		lastImage: -1,
+	}
+}
+
+func (m *StateMachine[C]) AddTransition(from, to string, priority int, condition func(C) bool) {
+	m.Transitions = append(m.Transitions, Transition[C]{
+		From:      from,
+		To:        to,
+		Priority:  priority,
+		Condition: condition,
+	})
+}
+
+func (m *StateMachine[C]) OnFrame(state string, frame int, callback func(C)) {
+	m.Events = append(m.Events, FrameEvent[C]{
+		State:    state,
+		Frame:    frame,
+		Callback: callback,
+	})
+}
+
+func (m *StateMachine[C]) State() string {
+	return m.Animator.Current
+}
+
+// Update follows the best transition, advances the animation and fires the
+// events of every image reached on the way.
+func (m *StateMachine[C]) Update(context C) {
+	var next *Transition[C]
+	for i, transition := range m.Transitions {
+		if transition.From != "" && transition.From != m.State() {
+			continue
+		}
+		if next != nil && transition.Priority <= next.Priority {
+			continue
+		}
+		if transition.Condition(context) {
+			next = &m.Transitions[i]
+		}
+	}
+
+	if next != nil {
+		m.Animator.Play(next.To)
+	}
+
+	entered := m.State() != m.lastState
+	start := m.Animator.Frame
+	m.Animator.Update()
+
+	if entered {
+		m.lastState = m.State()
+		m.lastImage = -1
+	}
+
+	// With a speed above 1 an update can move over several images, each of
+	// them gets its events. Below 1 a state can be entered without moving,
+	// its first image is reached all the same.
+	clip := m.Animator.Clip()
+	sequence := clip.sequence()
+	total := sequence.total()
+	first := 1
+	if entered && m.Animator.ticks == 0 {
+		first = 0
+	}
+
+	for tick := first; tick <= m.Animator.ticks; tick++ {
+		frame := start + tick
+		if clip.Loop {
+			frame %= total
+		} else if frame > total {
+			frame = total
+		}
+
+		image := sequence.imageAt(frame)
+		if image == m.lastImage {
+			continue
+		}
+		m.lastImage = image
+
+		for _, event := range m.Events {
+			if event.State == m.lastState && event.Frame == image {
+				event.Callback(context)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package animation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestStateMachineFiresSkippedImages(t *testing.T) {
+	tests := []struct {
+		name  string
+		clip  *Clip
+		speed float64
+		want  []int
+	}{
+		{
+			name: "normal speed",
+			clip: &Clip{Images: make([]*ebiten.Image, 4), ImageDuration: 1, Loop: true},
+			want: []int{1, 2, 3, 0, 1, 2},
+		},
+		{
+			name:  "fast loop",
+			clip:  &Clip{Images: make([]*ebiten.Image, 4), ImageDuration: 1, Loop: true},
+			speed: 3,
+			want:  []int{1, 2, 3, 0, 1, 2, 3, 0, 1, 2, 3, 0, 1, 2, 3, 0, 1, 2},
+		},
+		{
+			name:  "fast ping-pong",
+			clip:  &Clip{Images: make([]*ebiten.Image, 3), ImageDuration: 1, Mode: PingPong, Loop: true},
+			speed: 2,
+			want:  []int{1, 2, 1, 0, 1, 2, 1, 0, 1, 2, 1, 0},
+		},
+		{
+			name:  "slow",
+			clip:  &Clip{Images: make([]*ebiten.Image, 4), ImageDuration: 1, Loop: true},
+			speed: 0.5,
+			want:  []int{0, 1, 2, 3},
+		},
+		{
+			name:  "fast once",
+			clip:  &Clip{Images: make([]*ebiten.Image, 4), ImageDuration: 1},
+			speed: 5,
+			want:  []int{1, 2, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			animator := NewAnimator(map[string]*Clip{"run": test.clip}, "")
+			animator.Speed = test.speed
+			machine := NewStateMachine[*[]int](animator)
+			machine.AddTransition("", "run", 0, func(*[]int) bool { return true })
+			for image := range test.clip.Images {
+				image := image
+				machine.OnFrame("run", image, func(fired *[]int) { *fired = append(*fired, image) })
+			}
+
+			fired := []int{}
+			for i := 0; i < 6; i++ {
+				machine.Update(&fired)
+			}
+
+			if !reflect.DeepEqual(fired, test.want) {
+				t.Errorf("fired %v, want %v", fired, test.want)
+			}
+		})
+	}
+}
@@ -1,176 +1,313 @@
 package assets
 
 import (
-	"os"
-	"path/filepath"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/yuricorredor/platformer/animation"
+	"github.com/yuricorredor/platformer/types"
 )
 
+// Paths are relative to FS.
 const (
-	BasePath            = "assets/data/images/"
-	PlayerPath          = BasePath + "entities/player.png"
-	PlayerIdlePath      = BasePath + "entities/player/idle/"
-	PlayerJumpPath      = BasePath + "entities/player/jump/"
-	PlayerRunPath       = BasePath + "entities/player/run/"
-	PlayerSlidePath     = BasePath + "entities/player/slide/"
-	PlayerWallSlidePath = BasePath + "entities/player/wall_slide/"
-	EnemyPath           = BasePath + "entities/enemy.png"
-	EnemyIdlePath       = BasePath + "entities/enemy/idle"
-	EnemyRunPath        = BasePath + "entities/enemy/run"
-	DecorPath           = BasePath + "tiles/decor/"
-	GrassPath           = BasePath + "tiles/grass/"
-	LargeDecorPath      = BasePath + "tiles/large_decor/"
-	SpawnersPath        = BasePath + "tiles/spawners/"
-	StonePath           = BasePath + "tiles/stone/"
-	BackgroundPath      = BasePath + "background.png"
-	CloudsPath          = BasePath + "clouds/"
-	LeafsPath           = BasePath + "particles/leaf/"
-	ParticlePath        = BasePath + "particles/particle/"
-	GunPath             = BasePath + "gun.png"
-	ProjectilePath      = BasePath + "projectile.png"
+	BasePath     = "images/"
+	ManifestPath = "manifest.json"
 )
 
-var Assets = &AssetsType{
-	Images: map[string]Asset{
-		"player": {
-			Image:                load_image(PlayerPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"player_idle": {
-			Image:                load_images(PlayerIdlePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"player_jump": {
-			Image:                load_images(PlayerJumpPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"player_run": {
-			Image:                load_images(PlayerRunPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"player_slide": {
-			Image:                load_images(PlayerSlidePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"player_wall_slide": {
-			Image:                load_images(PlayerWallSlidePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"enemy": {
-			Image:                load_images(EnemyPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"enemy_idle": {
-			Image:                load_images(EnemyIdlePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"enemy_run": {
-			Image:                load_images(EnemyRunPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"decor": {
-			Image:                load_images(DecorPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"grass": {
-			Image:                load_images(GrassPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"large_decor": {
-			Image:                load_images(LargeDecorPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"spawners": {
-			Image:                load_images(SpawnersPath),
-			ShouldRenderOnGame:   false,
-			ShouldRenderOnEditor: true,
-		},
-		"stone": {
-			Image:                load_images(StonePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"background": {
-			Image:                load_image(BackgroundPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: true,
-		},
-		"clouds": {
-			Image:                load_images(CloudsPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: false,
-		},
-		"particle_leaf": {
-			Image:                load_images(LeafsPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: false,
-		},
-		"particle": {
-			Image:                load_images(ParticlePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: false,
-		},
-		"projectile": {
-			Image:                load_images(ProjectilePath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: false,
-		},
-		"gun": {
-			Image:                load_images(GunPath),
-			ShouldRenderOnGame:   true,
-			ShouldRenderOnEditor: false,
-		},
-	},
+// Assets is filled by Load, which has to run before any entity is created.
+var Assets *AssetsType
+
+// Load reads every asset of the manifest. Bad files don't stop the loading,
+// all of them are reported together in the returned error.
+func Load(fsys fs.FS) error {
+	assets, err := load_manifest(fsys, ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	Assets = assets
+	return nil
+}
+
+// Manifest describes every asset group of the game. Image paths, either a
+// single file or a directory of frames, and sheet paths are relative to
+// BasePath. An image group can also take its frames from a tag of a sheet.
+// Groups marked as Tile can be placed on maps with the editor.
+type Manifest struct {
+	Sheets map[string]ManifestSheet
+	Images map[string]ManifestImage
+}
+
+type ManifestSheet struct {
+	Path       string
+	Offset     types.Vector
+	TagOffsets map[string]types.Vector
+}
+
+type ManifestImage struct {
+	Path                 string
+	Sheet                string
+	Tag                  string
+	Tile                 bool
+	ShouldRenderOnGame   bool
+	ShouldRenderOnEditor bool
+	Animation            AnimationDefaults
+}
+
+// AnimationDefaults is how the frames of a group play when used as a clip.
+type AnimationDefaults struct {
+	ImageDuration int
+	Loop          bool
+	Offset        types.Vector
 }
 
 type Asset struct {
 	Image                []*ebiten.Image
+	Tile                 bool
 	ShouldRenderOnGame   bool
 	ShouldRenderOnEditor bool
+	Animation            AnimationDefaults
 }
 
 type AssetsType struct {
-	Images map[string]Asset
+	Images     map[string]Asset
+	Sheets     map[string]*SpriteSheet
+	Manifest   *Manifest
+	clips      map[string]*animation.Clip
+	sheetClips map[string]map[string]*animation.Clip
+}
+
+// Clip builds an animation clip out of an image group and its defaults. The
+// clip is built once and shared by every caller.
+func (a *AssetsType) Clip(name string) *animation.Clip {
+	if clip, ok := a.clips[name]; ok {
+		return clip
+	}
+
+	asset := a.Images[name]
+	clip := &animation.Clip{
+		Images:        asset.Image,
+		ImageDuration: asset.Animation.ImageDuration,
+		Loop:          asset.Animation.Loop,
+		Offset:        asset.Animation.Offset,
+	}
+	a.clips[name] = clip
+
+	return clip
+}
+
+// Clips builds the clips of every image group whose name starts with prefix,
+// keyed by the rest of the name, so "enemy_" gives "idle" for "enemy_idle".
+func (a *AssetsType) Clips(prefix string) map[string]*animation.Clip {
+	clips := map[string]*animation.Clip{}
+	for name := range a.Images {
+		if state, ok := strings.CutPrefix(name, prefix); ok && state != "" {
+			clips[state] = a.Clip(name)
+		}
+	}
+
+	return clips
+}
+
+// Tiles returns the names of the image groups that are tiles, sorted.
+func (a *AssetsType) Tiles() []string {
+	tiles := []string{}
+	for _, name := range sortedKeys(a.Images) {
+		if a.Images[name].Tile {
+			tiles = append(tiles, name)
+		}
+	}
+
+	return tiles
+}
+
+// Require checks that every image group of images has at least the given
+// number of frames and that every sheet of sheetTags has the given tags, for
+// the assets the game indexes directly. All problems are reported together.
+func (a *AssetsType) Require(images map[string]int, sheetTags map[string][]string) error {
+	errs := []error{}
+
+	for _, name := range sortedKeys(images) {
+		if frames := len(a.Images[name].Image); frames < images[name] {
+			errs = append(errs, fmt.Errorf("image %q: %d frames, the game needs %d", name, frames, images[name]))
+		}
+	}
+
+	for _, name := range sortedKeys(sheetTags) {
+		sheet, ok := a.Sheets[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("sheet %q: missing", name))
+			continue
+		}
+		for _, tag := range sheetTags[name] {
+			if len(sheet.Images(tag)) == 0 {
+				errs = append(errs, fmt.Errorf("sheet %q: no tag %q", name, tag))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-func load_image(path string) []*ebiten.Image {
-	image, _, err := ebitenutil.NewImageFromFile(path)
+// Animation is like Clip but returns a standalone animation, as used by
+// particles.
+func (a *AssetsType) Animation(name string) animation.Animation {
+	asset := a.Images[name]
+	return animation.Animation{
+		Images:        asset.Image,
+		ImageDuration: asset.Animation.ImageDuration,
+		Loop:          asset.Animation.Loop,
+		Offset:        asset.Animation.Offset,
+	}
+}
+
+// SheetClips turns the tags of a sheet into clips, with the offsets set in
+// the manifest. Like Clip, the result is shared.
+func (a *AssetsType) SheetClips(name string) map[string]*animation.Clip {
+	if clips, ok := a.sheetClips[name]; ok {
+		return clips
+	}
+
+	manifestSheet := a.Manifest.Sheets[name]
+	clips := a.Sheets[name].Clips(manifestSheet.Offset)
+	for tag, offset := range manifestSheet.TagOffsets {
+		if clip, ok := clips[tag]; ok {
+			clip.Offset = offset
+		}
+	}
+	a.sheetClips[name] = clips
+
+	return clips
+}
+
+func load_manifest(fsys fs.FS, path string) (*AssetsType, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(f).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	assets := &AssetsType{
+		Images:     map[string]Asset{},
+		Sheets:     map[string]*SpriteSheet{},
+		Manifest:   manifest,
+		clips:      map[string]*animation.Clip{},
+		sheetClips: map[string]map[string]*animation.Clip{},
+	}
+
+	errs := []error{}
+
+	for _, name := range sortedKeys(manifest.Sheets) {
+		sheet, err := LoadSpriteSheet(fsys, BasePath+manifest.Sheets[name].Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sheet %q: %w", name, err))
+			continue
+		}
+
+		assets.Sheets[name] = sheet
+	}
+
+	for _, name := range sortedKeys(manifest.Images) {
+		image := manifest.Images[name]
+		asset := Asset{
+			Tile:                 image.Tile,
+			ShouldRenderOnGame:   image.ShouldRenderOnGame,
+			ShouldRenderOnEditor: image.ShouldRenderOnEditor,
+			Animation:            image.Animation,
+		}
+
+		if image.Sheet != "" {
+			sheet, ok := assets.Sheets[image.Sheet]
+			if !ok {
+				if _, declared := manifest.Sheets[image.Sheet]; !declared {
+					errs = append(errs, fmt.Errorf("image %q: unknown sheet %q", name, image.Sheet))
+				}
+				continue
+			}
+			asset.Image = sheet.Images(image.Tag)
+			if len(asset.Image) == 0 {
+				errs = append(errs, fmt.Errorf("image %q: sheet %q has no tag %q", name, image.Sheet, image.Tag))
+				continue
+			}
+		} else {
+			images, err := load_images(fsys, BasePath+image.Path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("image %q: %w", name, err))
+			}
+			asset.Image = images
+		}
+
+		if len(asset.Image) == 0 {
+			errs = append(errs, fmt.Errorf("image %q: no frames", name))
+			continue
+		}
+
+		assets.Images[name] = asset
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return assets, nil
+}
+
+func load_image(fsys fs.FS, path string) (*ebiten.Image, error) {
+	image, _, err := ebitenutil.NewImageFromFileSystem(fsys, path)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
-	return []*ebiten.Image{image}
+	return image, nil
 }
 
-func load_images(path string) []*ebiten.Image {
+// load_images loads a single file or every file under a directory, in
+// lexical order. Every file that fails is reported.
+func load_images(fsys fs.FS, path string) ([]*ebiten.Image, error) {
 	images := []*ebiten.Image{}
+	errs := []error{}
+
+	err := fs.WalkDir(fsys, path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() {
-			image := load_image(path)
-			images = append(images, image...)
+		if !entry.IsDir() {
+			image, err := load_image(fsys, path)
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			images = append(images, image)
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		panic(err)
+		errs = append(errs, err)
+	}
+
+	return images, errors.Join(errs...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	return images
+	return keys
 }
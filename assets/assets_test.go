@@ -0,0 +1,81 @@
+package assets
+
+import (
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifestGroups(t *testing.T) {
+	if err := Load(FS); err != nil {
+		t.Fatal(err)
+	}
+
+	wantTiles := []string{"decor", "grass", "ice", "large_decor", "platform", "spawners", "spikes", "spring", "stone"}
+	if tiles := Assets.Tiles(); !reflect.DeepEqual(tiles, wantTiles) {
+		t.Errorf("tiles = %v, want %v", tiles, wantTiles)
+	}
+
+	states := []string{}
+	for state, clip := range Assets.Clips("enemy_") {
+		if clip != Assets.Clip("enemy_"+state) {
+			t.Errorf("clip %q is not the shared one", state)
+		}
+		states = append(states, state)
+	}
+	sort.Strings(states)
+
+	if wantStates := []string{"idle", "run"}; !reflect.DeepEqual(states, wantStates) {
+		t.Errorf("enemy states = %v, want %v", states, wantStates)
+	}
+}
+
+func TestLoadManifestReportsEveryError(t *testing.T) {
+	png, err := fs.ReadFile(FS, BasePath+"tiles/grass/0.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := `{
+		"Sheets": {
+			"ghost": {"Path": "ghost.json"}
+		},
+		"Images": {
+			"good": {"Path": "good.png"},
+			"missing": {"Path": "missing.png"},
+			"broken": {"Path": "broken.png"},
+			"frames": {"Path": "frames"},
+			"orphan": {"Sheet": "nowhere", "Tag": "idle"}
+		}
+	}`
+	fsys := fstest.MapFS{
+		ManifestPath:              {Data: []byte(manifest)},
+		BasePath + "good.png":     {Data: png},
+		BasePath + "broken.png":   {Data: []byte("not a png")},
+		BasePath + "frames/0.png": {Data: png},
+		BasePath + "frames/1.png": {Data: []byte("not a png either")},
+	}
+
+	_, err = load_manifest(fsys, ManifestPath)
+	if err == nil {
+		t.Fatal("no error")
+	}
+
+	for _, want := range []string{
+		`sheet "ghost"`,
+		`image "missing"`,
+		`image "broken"`,
+		`frames/1.png`,
+		`image "orphan": unknown sheet "nowhere"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), `image "good"`) {
+		t.Errorf("error mentions the good image:\n%v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+//go:embed data
+var embedded embed.FS
+
+// FS is where every game file is read from: images, maps and tuning files.
+// It defaults to the files embedded in the binary, see UseOverride.
+var FS fs.FS = mustSub(embedded, "data")
+
+// UseOverride makes files found under dir take precedence over the embedded
+// ones, so content can be patched without rebuilding the binary.
+func UseOverride(dir string) {
+	FS = &OverlayFS{
+		Override: os.DirFS(dir),
+		Base:     mustSub(embedded, "data"),
+	}
+}
+
+// OverlayFS reads files from Override when they exist there and from Base
+// otherwise. Directories list the entries of both. Override errors other than
+// a missing file are returned rather than hidden behind Base.
+type OverlayFS struct {
+	Override fs.FS
+	Base     fs.FS
+}
+
+func (o *OverlayFS) Open(name string) (fs.File, error) {
+	f, err := o.Override.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return o.Base.Open(name)
+}
+
+func (o *OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	overrideEntries, overrideErr := fs.ReadDir(o.Override, name)
+	if overrideErr != nil && !errors.Is(overrideErr, fs.ErrNotExist) {
+		return nil, overrideErr
+	}
+	baseEntries, baseErr := fs.ReadDir(o.Base, name)
+	if overrideErr != nil && baseErr != nil {
+		return nil, baseErr
+	}
+
+	entries := map[string]fs.DirEntry{}
+	for _, entry := range baseEntries {
+		entries[entry.Name()] = entry
+	}
+	for _, entry := range overrideEntries {
+		entries[entry.Name()] = entry
+	}
+
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+
+	return merged, nil
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
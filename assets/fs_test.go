@@ -0,0 +1,90 @@
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// brokenFS fails every open with err.
+type brokenFS struct{ err error }
+
+func (b brokenFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: b.err}
+}
+
+func TestOverlayPrefersOverride(t *testing.T) {
+	overlay := &OverlayFS{
+		Override: fstest.MapFS{"maps/0.json": {Data: []byte("override")}},
+		Base: fstest.MapFS{
+			"maps/0.json": {Data: []byte("base")},
+			"maps/1.json": {Data: []byte("base")},
+		},
+	}
+
+	for name, want := range map[string]string{"maps/0.json": "override", "maps/1.json": "base"} {
+		data, err := fs.ReadFile(overlay, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	if _, err := fs.ReadFile(overlay, "maps/2.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestOverlayMergesDirectories(t *testing.T) {
+	overlay := &OverlayFS{
+		Override: fstest.MapFS{
+			"maps/0.json": {},
+			"maps/2.json": {},
+		},
+		Base: fstest.MapFS{
+			"maps/0.json": {},
+			"maps/1.json": {},
+			"sfx/hit.wav": {},
+		},
+	}
+
+	for dir, want := range map[string][]string{
+		"maps": {"0.json", "1.json", "2.json"},
+		"sfx":  {"hit.wav"},
+	} {
+		entries, err := fs.ReadDir(overlay, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%s lists %v, want %v", dir, names, want)
+		}
+	}
+
+	if _, err := fs.ReadDir(overlay, "music"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing directory error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestOverlayReturnsOverrideErrors(t *testing.T) {
+	overlay := &OverlayFS{
+		Override: brokenFS{fs.ErrPermission},
+		Base:     fstest.MapFS{"maps/0.json": {Data: []byte("base")}},
+	}
+
+	if _, err := fs.ReadFile(overlay, "maps/0.json"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("open error = %v, want fs.ErrPermission", err)
+	}
+	if _, err := fs.ReadDir(overlay, "maps"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("read dir error = %v, want fs.ErrPermission", err)
+	}
+}
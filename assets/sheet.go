@@ -0,0 +1,223 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"math"
+	pathpkg "path"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/yuricorredor/platformer/animation"
+	"github.com/yuricorredor/platformer/types"
+)
+
+// TicksPerSecond converts Aseprite frame durations, given in milliseconds,
+// into game updates.
+const TicksPerSecond = 60
+
+// SpriteSheet is a single image sliced into frames by an Aseprite JSON export.
+type SpriteSheet struct {
+	Frames []SheetFrame
+	Tags   []SheetTag
+}
+
+type SheetFrame struct {
+	Image    *ebiten.Image
+	Duration int
+}
+
+type SheetTag struct {
+	Name string
+	From int
+	To   int
+	Mode animation.Mode
+	Loop bool
+}
+
+type asepriteRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type asepriteFrame struct {
+	Frame            asepriteRect `json:"frame"`
+	Rotated          bool         `json:"rotated"`
+	Trimmed          bool         `json:"trimmed"`
+	SpriteSourceSize asepriteRect `json:"spriteSourceSize"`
+	SourceSize       asepriteRect `json:"sourceSize"`
+	Duration         int          `json:"duration"`
+}
+
+type asepriteTag struct {
+	Name      string `json:"name"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Direction string `json:"direction"`
+	Repeat    string `json:"repeat"`
+}
+
+type asepriteSheet struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image     string        `json:"image"`
+		FrameTags []asepriteTag `json:"frameTags"`
+	} `json:"meta"`
+}
+
+// LoadSpriteSheet reads an Aseprite JSON export. Both the array and the hash
+// frame layouts are supported, and the sheet image is looked up next to the
+// JSON file.
+func LoadSpriteSheet(fsys fs.FS, path string) (*SpriteSheet, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet asepriteSheet
+	if err := json.Unmarshal(data, &sheet); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	frames, err := decodeAsepriteFrames(sheet.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	sheetImage, _, err := ebitenutil.NewImageFromFileSystem(fsys, pathpkg.Join(pathpkg.Dir(path), sheet.Meta.Image))
+	if err != nil {
+		return nil, err
+	}
+
+	spriteSheet := &SpriteSheet{}
+	for _, frame := range frames {
+		if frame.Rotated {
+			return nil, fmt.Errorf("%s: rotated frames are not supported", path)
+		}
+
+		spriteSheet.Frames = append(spriteSheet.Frames, SheetFrame{
+			Image:    sliceFrame(sheetImage, frame),
+			Duration: int(math.Max(1, math.Round(float64(frame.Duration)*TicksPerSecond/1000))),
+		})
+	}
+
+	for _, tag := range sheet.Meta.FrameTags {
+		if tag.From < 0 || tag.To >= len(frames) || tag.From > tag.To {
+			return nil, fmt.Errorf("%s: tag %q is out of range", path, tag.Name)
+		}
+
+		spriteSheet.Tags = append(spriteSheet.Tags, SheetTag{
+			Name: tag.Name,
+			From: tag.From,
+			To:   tag.To,
+			Mode: tagMode(tag.Direction),
+			Loop: tag.Repeat == "" || tag.Repeat == "0",
+		})
+	}
+
+	return spriteSheet, nil
+}
+
+// Clips turns every tag of the sheet into an animation clip of the same name.
+func (s *SpriteSheet) Clips(offset types.Vector) map[string]*animation.Clip {
+	clips := map[string]*animation.Clip{}
+	for _, tag := range s.Tags {
+		clip := &animation.Clip{
+			Mode:   tag.Mode,
+			Loop:   tag.Loop,
+			Offset: offset,
+		}
+
+		for _, frame := range s.Frames[tag.From : tag.To+1] {
+			clip.Images = append(clip.Images, frame.Image)
+			clip.Durations = append(clip.Durations, frame.Duration)
+		}
+		clip.ImageDuration = clip.Durations[0]
+
+		clips[tag.Name] = clip
+	}
+
+	return clips
+}
+
+// Images returns the frames of a tag, in sheet order.
+func (s *SpriteSheet) Images(tagName string) []*ebiten.Image {
+	images := []*ebiten.Image{}
+	for _, tag := range s.Tags {
+		if tag.Name == tagName {
+			for _, frame := range s.Frames[tag.From : tag.To+1] {
+				images = append(images, frame.Image)
+			}
+		}
+	}
+
+	return images
+}
+
+// decodeAsepriteFrames keeps the frames in file order, which matters for the
+// hash layout since tags refer to frames by index.
+func decodeAsepriteFrames(raw json.RawMessage) ([]asepriteFrame, error) {
+	frames := []asepriteFrame{}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		err := json.Unmarshal(raw, &frames)
+		return frames, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		var frame asepriteFrame
+		if err := decoder.Decode(&frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// sliceFrame copies a frame into its own image, so its bounds start at the
+// origin like images loaded from single files. Trimmed frames are put back at
+// their place in the untrimmed canvas.
+func sliceFrame(sheetImage *ebiten.Image, frame asepriteFrame) *ebiten.Image {
+	rect := image.Rect(frame.Frame.X, frame.Frame.Y, frame.Frame.X+frame.Frame.W, frame.Frame.Y+frame.Frame.H)
+	subImage := sheetImage.SubImage(rect).(*ebiten.Image)
+
+	width, height := frame.Frame.W, frame.Frame.H
+	options := &ebiten.DrawImageOptions{}
+	if frame.Trimmed {
+		width, height = frame.SourceSize.W, frame.SourceSize.H
+		options.GeoM.Translate(float64(frame.SpriteSourceSize.X), float64(frame.SpriteSourceSize.Y))
+	}
+
+	canvas := ebiten.NewImage(width, height)
+	canvas.DrawImage(subImage, options)
+
+	return canvas
+}
+
+func tagMode(direction string) animation.Mode {
+	switch direction {
+	case "reverse":
+		return animation.Reverse
+	case "pingpong":
+		return animation.PingPong
+	case "pingpong_reverse":
+		return animation.ReversePingPong
+	}
+
+	return animation.Forward
+}
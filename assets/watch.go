@@ -0,0 +1,74 @@
+package assets
+
+import (
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// Watcher polls the modification times of every file under a set of paths.
+// Files embedded in the binary never change, so it only sees edits made in
+// the override directory, see UseOverride.
+type Watcher struct {
+	fsys   fs.FS
+	paths  []string
+	mtimes map[string]time.Time
+}
+
+func NewWatcher(fsys fs.FS, paths ...string) *Watcher {
+	w := &Watcher{
+		fsys:  fsys,
+		paths: paths,
+	}
+	w.mtimes = w.scan()
+
+	return w
+}
+
+// Changed returns the files that were modified, created or removed since the
+// last call, sorted.
+func (w *Watcher) Changed() []string {
+	mtimes := w.scan()
+	changed := []string{}
+
+	for path, mtime := range mtimes {
+		if previous, ok := w.mtimes[path]; !ok || !previous.Equal(mtime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.mtimes {
+		if _, ok := mtimes[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	w.mtimes = mtimes
+	sort.Strings(changed)
+
+	return changed
+}
+
+func (w *Watcher) scan() map[string]time.Time {
+	mtimes := map[string]time.Time{}
+
+	for _, root := range w.paths {
+		fs.WalkDir(w.fsys, root, func(path string, entry fs.DirEntry, err error) error {
+			// Files that can't be read right now, for instance because an
+			// editor is writing them, are reported as removed and will show
+			// up again on a later scan.
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			mtimes[path] = info.ModTime()
+
+			return nil
+		})
+	}
+
+	return mtimes
+}
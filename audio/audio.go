@@ -0,0 +1,278 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/yuricorredor/platformer/sfxr"
+	"github.com/yuricorredor/platformer/types"
+)
+
+const (
+	SampleRate = 44100
+	// MaxVoices is how many copies of the same sound can play at once. Past
+	// that the oldest one is cut.
+	MaxVoices = 4
+)
+
+// VolumesFile, in the sounds directory, gives the base volume of sounds by
+// name, from 0 to 1. Sounds missing from it play at full volume.
+const VolumesFile = "volumes.json"
+
+// Player plays sounds by name. The simulation only talks to this interface
+// so it can run without an audio device, see Null.
+type Player interface {
+	Play(name string)
+	// PlayAt plays a sound coming from a point of the world, heard from the
+	// last position given to SetListener.
+	PlayAt(name string, position types.Vector)
+	SetListener(position types.Vector)
+	// Duck lowers the music for a few frames so a big hit stands out.
+	Duck(frames int)
+}
+
+// Null is the Player of headless runs, it ignores every sound.
+type Null struct{}
+
+func (Null) Play(name string)                          {}
+func (Null) PlayAt(name string, position types.Vector) {}
+func (Null) SetListener(position types.Vector)         {}
+func (Null) Duck(frames int)                           {}
+
+// Falloff describes how positional sounds fade with distance. Sounds closer
+// than Near play at full volume, the volume then drops linearly down to
+// nothing at Cutoff, past which sounds aren't played at all. Pan is the
+// horizontal distance at which a sound only comes out of one side.
+type Falloff struct {
+	Near   float64
+	Cutoff float64
+	Pan    float64
+}
+
+var DefaultFalloff = Falloff{
+	Near:   96,
+	Cutoff: 400,
+	Pan:    240,
+}
+
+// At returns the volume, from 0 to 1, and the pan, from -1 to 1, of a sound
+// at position heard from listener, and whether it is close enough to be heard
+// at all.
+func (f Falloff) At(listener, position types.Vector) (volume, pan float64, audible bool) {
+	distanceX := position.X - listener.X
+	distanceY := position.Y - listener.Y
+	distance := math.Hypot(distanceX, distanceY)
+	if distance >= f.Cutoff {
+		return 0, 0, false
+	}
+
+	volume = 1
+	if distance > f.Near {
+		volume = 1 - (distance-f.Near)/(f.Cutoff-f.Near)
+	}
+
+	if f.Pan > 0 {
+		pan = math.Max(-1, math.Min(1, distanceX/f.Pan))
+	}
+
+	return volume, pan, true
+}
+
+// Sounds plays the sounds of a directory through an ebiten audio context.
+// Music, when set, is what Duck acts on.
+type Sounds struct {
+	Volumes  map[string]float64
+	Falloff  Falloff
+	Listener types.Vector
+	Mixer    *Mixer
+	Music    *Music
+	sounds   map[string][]byte
+	voices   map[string][]*audio.Player
+}
+
+var context *audio.Context
+
+// sharedContext returns the audio context of the game, ebiten only allows one.
+func sharedContext() *audio.Context {
+	if context == nil {
+		context = audio.NewContext(SampleRate)
+	}
+
+	return context
+}
+
+// Load decodes every .wav file of dir, each one named after its file without
+// the extension. A .json sfxr preset is synthesized instead. A .wav wins over
+// a preset of the same name, unless the preset sets "Override": true.
+func Load(fsys fs.FS, dir string, mixer *Mixer) (*Sounds, error) {
+	s := &Sounds{
+		Volumes: map[string]float64{},
+		Falloff: DefaultFalloff,
+		Mixer:   mixer,
+		voices:  map[string][]*audio.Player{},
+	}
+
+	if err := s.Reload(fsys, dir); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload decodes the sounds of dir again. On error the current sounds are
+// kept.
+func (s *Sounds) Reload(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	sounds := map[string][]byte{}
+	presets := map[string][]byte{}
+	overrides := map[string]bool{}
+	volumes := map[string]float64{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+
+		switch {
+		case entry.IsDir():
+			continue
+		case entry.Name() == VolumesFile:
+			data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &volumes); err != nil {
+				return fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+		case path.Ext(entry.Name()) == ".wav":
+			data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+
+			pcm, err := decodeWav(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+			sounds[name] = pcm
+		case path.Ext(entry.Name()) == ".json":
+			params, err := sfxr.LoadParams(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+
+			pcm, err := decodeWav(sfxr.Render(params))
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+			presets[name] = pcm
+			overrides[name], err = presetOverrides(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+		}
+	}
+
+	for name, pcm := range presets {
+		if _, ok := sounds[name]; !ok || overrides[name] {
+			sounds[name] = pcm
+		}
+	}
+	for name := range sounds {
+		if _, ok := volumes[name]; !ok {
+			volumes[name] = 1
+		}
+		volumes[name] = clamp(volumes[name])
+	}
+
+	s.sounds = sounds
+	s.Volumes = volumes
+	return nil
+}
+
+// Play starts the named sound, on top of any sound already playing. Unknown
+// names are ignored.
+func (s *Sounds) Play(name string) {
+	s.play(name, 1, 0)
+}
+
+func (s *Sounds) PlayAt(name string, position types.Vector) {
+	if volume, pan, audible := s.Falloff.At(s.Listener, position); audible {
+		s.play(name, volume, pan)
+	}
+}
+
+func (s *Sounds) SetListener(position types.Vector) {
+	s.Listener = position
+}
+
+func (s *Sounds) Duck(frames int) {
+	if s.Music != nil {
+		s.Music.Duck(frames)
+	}
+}
+
+func (s *Sounds) play(name string, volume, pan float64) {
+	pcm, ok := s.sounds[name]
+	if !ok {
+		return
+	}
+
+	voices := []*audio.Player{}
+	for _, voice := range s.voices[name] {
+		if voice.IsPlaying() {
+			voices = append(voices, voice)
+		} else {
+			voice.Close()
+		}
+	}
+	if len(voices) >= MaxVoices {
+		voices[0].Close()
+		voices = voices[1:]
+	}
+
+	voice, err := sharedContext().NewPlayer(newPanStream(pcm, pan))
+	if err != nil {
+		return
+	}
+	voice.SetVolume(s.Volumes[name] * volume * s.Mixer.Master * s.Mixer.Sfx)
+	voice.Play()
+
+	s.voices[name] = append(voices, voice)
+}
+
+// presetOverrides reads whether a preset opts in to replacing the .wav of the
+// same name. The field is not part of the sfxr params.
+func presetOverrides(fsys fs.FS, name string) (bool, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return false, err
+	}
+
+	var preset struct{ Override bool }
+	if err := json.Unmarshal(data, &preset); err != nil {
+		return false, err
+	}
+
+	return preset.Override, nil
+}
+
+// decodeWav turns the content of a .wav file into PCM at SampleRate, as the
+// audio context plays it.
+func decodeWav(data []byte) ([]byte, error) {
+	stream, err := wav.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(stream)
+}
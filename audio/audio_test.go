@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yuricorredor/platformer/sfxr"
+)
+
+func TestReloadVolumes(t *testing.T) {
+	wav := sfxr.Render(sfxr.DefaultParams())
+	fsys := fstest.MapFS{
+		"sfx/jump.wav":       {Data: wav},
+		"sfx/hit.wav":        {Data: wav},
+		"sfx/shoot.wav":      {Data: wav},
+		"sfx/" + VolumesFile: {Data: []byte(`{"jump": 0.5, "shoot": 3}`)},
+	}
+
+	sounds, err := Load(fsys, "sfx", DefaultMixer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"jump": 0.5, "hit": 1, "shoot": 1}
+	for name, volume := range want {
+		if sounds.Volumes[name] != volume {
+			t.Errorf("volume of %q = %v, want %v", name, sounds.Volumes[name], volume)
+		}
+	}
+	if _, ok := sounds.sounds["volumes"]; ok {
+		t.Error("the volumes file was loaded as a sound")
+	}
+}
+
+func TestReloadPresetPrecedence(t *testing.T) {
+	wav := sfxr.Render(sfxr.DefaultParams())
+	preset := []byte(`{"Wave": "noise", "Frequency": 900}`)
+	override := []byte(`{"Wave": "noise", "Frequency": 900, "Override": true}`)
+
+	tests := []struct {
+		name       string
+		files      fstest.MapFS
+		wantPreset bool
+	}{
+		{name: "wav only", files: fstest.MapFS{"sfx/hit.wav": {Data: wav}}},
+		{name: "preset only", files: fstest.MapFS{"sfx/hit.json": {Data: preset}}, wantPreset: true},
+		{name: "wav wins", files: fstest.MapFS{"sfx/hit.wav": {Data: wav}, "sfx/hit.json": {Data: preset}}},
+		{name: "override", files: fstest.MapFS{"sfx/hit.wav": {Data: wav}, "sfx/hit.json": {Data: override}}, wantPreset: true},
+	}
+
+	fromWav, err := decodeWav(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sounds, err := Load(test.files, "sfx", DefaultMixer())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pcm, ok := sounds.sounds["hit"]
+			if !ok {
+				t.Fatal("hit was not loaded")
+			}
+			if gotPreset := !bytes.Equal(pcm, fromWav); gotPreset != test.wantPreset {
+				t.Errorf("played the preset = %v, want %v", gotPreset, test.wantPreset)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package audio
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Mixer holds the volumes the player picked, from 0 to 1. Master scales
+// every other channel.
+type Mixer struct {
+	Master   float64
+	Music    float64
+	Sfx      float64
+	Ambience float64
+}
+
+func DefaultMixer() *Mixer {
+	return &Mixer{
+		Master:   1,
+		Music:    0.7,
+		Sfx:      1,
+		Ambience: 0.7,
+	}
+}
+
+// SettingsPath is where the mixer is saved, in the user config directory.
+func SettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "platformer", "settings.json"), nil
+}
+
+// LoadMixer reads the mixer saved at path, falling back to the defaults when
+// nothing was saved yet.
+func LoadMixer(path string) (*Mixer, error) {
+	mixer := DefaultMixer()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mixer, nil
+	}
+	if err != nil {
+		return mixer, err
+	}
+
+	if err := json.Unmarshal(data, mixer); err != nil {
+		return DefaultMixer(), err
+	}
+	for _, volume := range []*float64{&mixer.Master, &mixer.Music, &mixer.Sfx, &mixer.Ambience} {
+		*volume = clamp(*volume)
+	}
+
+	return mixer, nil
+}
+
+func (m *Mixer) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Adjust changes a volume by step, keeping it between 0 and 1.
+func Adjust(volume *float64, step float64) {
+	*volume = clamp(*volume + step)
+}
+
+func clamp(volume float64) float64 {
+	return math.Max(0, math.Min(1, volume))
+}
@@ -0,0 +1,24 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMixerClamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(`{"Master": 3, "Music": -1, "Sfx": 0.5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mixer, err := LoadMixer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Mixer{Master: 1, Music: 0, Sfx: 0.5, Ambience: DefaultMixer().Ambience}
+	if *mixer != want {
+		t.Errorf("mixer = %+v, want %+v", *mixer, want)
+	}
+}
@@ -0,0 +1,281 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// bytesPerFrame is the size of one stereo 16-bit sample, as decoded.
+const bytesPerFrame = 4
+
+const (
+	CrossfadeFrames = 90
+	// DuckVolume is how loud the music gets while ducked, DuckFadeFrames how
+	// long it takes to get there and back.
+	DuckVolume     = 0.35
+	DuckFadeFrames = 6
+)
+
+// Music plays a looping track and an ambience loop on top of it, crossfading
+// whenever either of them changes. Both are .wav files of a directory. A
+// track named "x" may come with "x_intro.wav", played once before "x.wav"
+// starts looping. A playlist plays its tracks in turn instead, crossfading
+// to the next one as each ends, and starts over after the last.
+type Music struct {
+	Mixer    *Mixer
+	fsys     fs.FS
+	dir      string
+	tracks   map[string]*track
+	music    channel
+	ambience channel
+	playlist []string
+	next     int
+	duck     int
+	level    float64
+}
+
+type track struct {
+	pcm         []byte
+	introLength int64
+}
+
+// duration is how long the track plays before looping, intro included.
+func (t *track) duration() time.Duration {
+	return time.Duration(len(t.pcm)) * time.Second / (SampleRate * bytesPerFrame)
+}
+
+// channel holds the voice being faded in last, and the voices fading out
+// before it.
+type channel struct {
+	voices []*musicVoice
+}
+
+type musicVoice struct {
+	name   string
+	player *audio.Player
+	fade   float64
+	target float64
+}
+
+func NewMusic(fsys fs.FS, dir string, mixer *Mixer) *Music {
+	return &Music{
+		Mixer:  mixer,
+		fsys:   fsys,
+		dir:    dir,
+		tracks: map[string]*track{},
+		level:  1,
+	}
+}
+
+// PlayTrack crossfades to the named track. Playing the track already on
+// keeps it going, an empty name fades the music out.
+func (m *Music) PlayTrack(name string) error {
+	m.playlist = nil
+	return m.play(&m.music, name)
+}
+
+// PlayPlaylist crossfades to the first track of names, unless the same
+// playlist is already on. A single track just loops, as with PlayTrack.
+func (m *Music) PlayPlaylist(names []string) error {
+	if len(names) == 0 {
+		return m.PlayTrack("")
+	}
+	if equalNames(names, m.playlist) {
+		return nil
+	}
+
+	m.playlist = append([]string{}, names...)
+	m.next = 1 % len(names)
+	return m.play(&m.music, names[0])
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PlayAmbience is PlayTrack for the ambience loop.
+func (m *Music) PlayAmbience(name string) error {
+	return m.play(&m.ambience, name)
+}
+
+func (m *Music) Duck(frames int) {
+	m.duck = int(math.Max(float64(m.duck), float64(frames)))
+}
+
+// Update moves the fades and the ducking along, once per game update.
+func (m *Music) Update() {
+	target := 1.0
+	if m.duck > 0 {
+		m.duck--
+		target = DuckVolume
+	}
+	step := (1 - DuckVolume) / DuckFadeFrames
+	if m.level < target {
+		m.level = math.Min(target, m.level+step)
+	} else {
+		m.level = math.Max(target, m.level-step)
+	}
+
+	m.advancePlaylist()
+
+	m.music.update(m.level * m.Mixer.Master * m.Mixer.Music)
+	m.ambience.update(m.level * m.Mixer.Master * m.Mixer.Ambience)
+}
+
+// advancePlaylist starts the next track of the playlist once the current one
+// is close enough to its end for the crossfade to finish in time.
+func (m *Music) advancePlaylist() {
+	if len(m.music.voices) == 0 {
+		return
+	}
+
+	voice := m.music.voices[len(m.music.voices)-1]
+	t, ok := m.tracks[voice.name]
+	if !ok || voice.target == 0 {
+		return
+	}
+
+	name, ok := m.nextInPlaylist(voice.player.Current(), t.duration())
+	if !ok {
+		return
+	}
+	if err := m.play(&m.music, name); err != nil {
+		m.playlist = nil
+	}
+}
+
+// nextInPlaylist returns the track to crossfade to once the current one has
+// played for position out of duration, if the crossfade has to start now,
+// and moves the playlist on to the track after it.
+func (m *Music) nextInPlaylist(position, duration time.Duration) (string, bool) {
+	if len(m.playlist) < 2 {
+		return "", false
+	}
+
+	crossfade := time.Duration(CrossfadeFrames) * time.Second / 60
+	if position < duration-crossfade {
+		return "", false
+	}
+
+	name := m.playlist[m.next]
+	m.next = (m.next + 1) % len(m.playlist)
+
+	return name, true
+}
+
+func (m *Music) play(c *channel, name string) error {
+	if c.current() == name {
+		return nil
+	}
+
+	for _, voice := range c.voices {
+		voice.target = 0
+	}
+	if name == "" {
+		return nil
+	}
+
+	t, err := m.load(name)
+	if err != nil {
+		return err
+	}
+
+	loop := audio.NewInfiniteLoopWithIntro(bytes.NewReader(t.pcm), t.introLength, int64(len(t.pcm))-t.introLength)
+	player, err := sharedContext().NewPlayer(loop)
+	if err != nil {
+		return err
+	}
+	player.SetVolume(0)
+	player.Play()
+
+	c.voices = append(c.voices, &musicVoice{
+		name:   name,
+		player: player,
+		target: 1,
+	})
+
+	return nil
+}
+
+func (m *Music) load(name string) (*track, error) {
+	if t, ok := m.tracks[name]; ok {
+		return t, nil
+	}
+
+	loop, err := m.decode(name + ".wav")
+	if err != nil {
+		return nil, err
+	}
+
+	intro, err := m.decode(name + "_intro.wav")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	t := &track{
+		pcm:         append(intro, loop...),
+		introLength: int64(len(intro)),
+	}
+	m.tracks[name] = t
+
+	return t, nil
+}
+
+func (m *Music) decode(file string) ([]byte, error) {
+	data, err := fs.ReadFile(m.fsys, path.Join(m.dir, file))
+	if err != nil {
+		return nil, err
+	}
+
+	pcm, err := decodeWav(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return pcm, nil
+}
+
+func (c *channel) current() string {
+	if len(c.voices) == 0 || c.voices[len(c.voices)-1].target == 0 {
+		return ""
+	}
+
+	return c.voices[len(c.voices)-1].name
+}
+
+func (c *channel) update(volume float64) {
+	voices := []*musicVoice{}
+	for _, voice := range c.voices {
+		if voice.fade < voice.target {
+			voice.fade = math.Min(voice.target, voice.fade+1.0/CrossfadeFrames)
+		} else {
+			voice.fade = math.Max(voice.target, voice.fade-1.0/CrossfadeFrames)
+		}
+
+		if voice.fade == 0 && voice.target == 0 {
+			voice.player.Close()
+			continue
+		}
+
+		voice.player.SetVolume(voice.fade * volume)
+		voices = append(voices, voice)
+	}
+
+	c.voices = voices
+}
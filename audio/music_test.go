@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yuricorredor/platformer/assets"
+)
+
+func TestShippedTracksLoad(t *testing.T) {
+	music := NewMusic(assets.FS, "music", DefaultMixer())
+
+	for _, name := range []string{"meadow", "ridge", "wind"} {
+		track, err := music.load(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if track.duration() < time.Duration(CrossfadeFrames)*time.Second/60 {
+			t.Errorf("%s lasts %v, shorter than a crossfade", name, track.duration())
+		}
+	}
+}
+
+func TestNextInPlaylist(t *testing.T) {
+	music := NewMusic(assets.FS, "music", DefaultMixer())
+	music.playlist = []string{"a", "b", "c"}
+	music.next = 1
+
+	duration := 10 * time.Second
+	crossfade := time.Duration(CrossfadeFrames) * time.Second / 60
+	start := duration - crossfade
+
+	if name, ok := music.nextInPlaylist(start-time.Millisecond, duration); ok {
+		t.Errorf("moved on to %q before the crossfade", name)
+	}
+
+	for _, want := range []string{"b", "c", "a", "b"} {
+		name, ok := music.nextInPlaylist(start, duration)
+		if !ok || name != want {
+			t.Fatalf("next track = %q, %v, want %q", name, ok, want)
+		}
+	}
+
+	music.playlist = []string{"a"}
+	music.next = 0
+	if name, ok := music.nextInPlaylist(duration, duration); ok {
+		t.Errorf("a single track playlist moved on to %q", name)
+	}
+}
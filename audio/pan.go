@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// panStream plays decoded PCM, 16 bit little endian stereo, with the left and
+// right channels scaled for a pan between -1, full left, and 1, full right.
+type panStream struct {
+	source *bytes.Reader
+	left   float64
+	right  float64
+}
+
+func newPanStream(pcm []byte, pan float64) *panStream {
+	left, right := panGains(pan)
+
+	return &panStream{
+		source: bytes.NewReader(pcm),
+		left:   left,
+		right:  right,
+	}
+}
+
+// panGains uses an equal power pan law, boosted so that a centered sound
+// keeps its original volume on both channels.
+func panGains(pan float64) (left, right float64) {
+	angle := (pan + 1) * math.Pi / 4
+
+	return math.Min(1, math.Sqrt2*math.Cos(angle)), math.Min(1, math.Sqrt2*math.Sin(angle))
+}
+
+func (p *panStream) Read(buf []byte) (int, error) {
+	// Only whole frames, so samples are never split between two reads.
+	buf = buf[:len(buf)/4*4]
+
+	n, err := io.ReadFull(p.source, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	n = n / 4 * 4
+
+	for i := 0; i < n; i += 4 {
+		p.scale(buf[i:i+2], p.left)
+		p.scale(buf[i+2:i+4], p.right)
+	}
+
+	return n, err
+}
+
+func (p *panStream) Seek(offset int64, whence int) (int64, error) {
+	return p.source.Seek(offset, whence)
+}
+
+func (p *panStream) scale(sample []byte, gain float64) {
+	value := float64(int16(binary.LittleEndian.Uint16(sample))) * gain
+	binary.LittleEndian.PutUint16(sample, uint16(int16(value)))
+}
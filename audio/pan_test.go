@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestFalloff(t *testing.T) {
+	falloff := Falloff{Near: 100, Cutoff: 300, Pan: 200}
+	listener := types.Vector{X: 50, Y: 50}
+
+	tests := []struct {
+		name    string
+		offset  types.Vector
+		volume  float64
+		pan     float64
+		audible bool
+	}{
+		{name: "on the listener", offset: types.Vector{}, volume: 1, audible: true},
+		{name: "inside near", offset: types.Vector{Y: -80}, volume: 1, audible: true},
+		{name: "at near", offset: types.Vector{X: 100}, volume: 1, pan: 0.5, audible: true},
+		{name: "halfway to cutoff", offset: types.Vector{X: -200}, volume: 0.5, pan: -1, audible: true},
+		{name: "diagonal", offset: types.Vector{X: 120, Y: 160}, volume: 0.5, pan: 0.6, audible: true},
+		{name: "just inside cutoff", offset: types.Vector{Y: 299}, volume: 0.005, audible: true},
+		{name: "at cutoff", offset: types.Vector{X: 300}},
+		{name: "past cutoff", offset: types.Vector{X: -1000}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position := types.Vector{X: listener.X + test.offset.X, Y: listener.Y + test.offset.Y}
+			volume, pan, audible := falloff.At(listener, position)
+
+			if audible != test.audible {
+				t.Fatalf("audible = %v, want %v", audible, test.audible)
+			}
+			if !near(volume, test.volume) {
+				t.Errorf("volume = %v, want %v", volume, test.volume)
+			}
+			if !near(pan, test.pan) {
+				t.Errorf("pan = %v, want %v", pan, test.pan)
+			}
+		})
+	}
+
+	if _, pan, _ := (Falloff{Near: 100, Cutoff: 300}).At(listener, types.Vector{X: 150, Y: 50}); pan != 0 {
+		t.Errorf("pan without a pan distance = %v, want 0", pan)
+	}
+}
+
+func TestPanGains(t *testing.T) {
+	tests := []struct {
+		pan         float64
+		left, right float64
+	}{
+		{pan: 0, left: 1, right: 1},
+		{pan: -1, left: 1, right: 0},
+		{pan: 1, left: 0, right: 1},
+		{pan: 0.5, left: math.Sqrt2 * math.Cos(3*math.Pi/8), right: 1},
+		{pan: -0.5, left: 1, right: math.Sqrt2 * math.Cos(3*math.Pi/8)},
+	}
+
+	for _, test := range tests {
+		left, right := panGains(test.pan)
+		if !near(left, test.left) || !near(right, test.right) {
+			t.Errorf("pan %v: gains %v, %v, want %v, %v", test.pan, left, right, test.left, test.right)
+		}
+	}
+}
+
+func TestPanStream(t *testing.T) {
+	// Two stereo frames of full scale samples.
+	pcm := []byte{0xff, 0x7f, 0xff, 0x7f, 0x00, 0x80, 0x00, 0x80}
+
+	buf := make([]byte, len(pcm))
+	n, err := newPanStream(append([]byte{}, pcm...), 0).Read(buf)
+	if err != nil || n != len(pcm) {
+		t.Fatalf("read %d bytes, %v", n, err)
+	}
+	if string(buf) != string(pcm) {
+		t.Errorf("centered sound = %x, want the source %x", buf, pcm)
+	}
+
+	n, _ = newPanStream(append([]byte{}, pcm...), 1).Read(buf)
+	if want := []byte{0, 0, 0xff, 0x7f, 0, 0, 0x00, 0x80}; string(buf[:n]) != string(want) {
+		t.Errorf("sound panned right = %x, want %x", buf[:n], want)
+	}
+}
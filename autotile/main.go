@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/tilemap"
+)
+
+var (
+	RULES_PATH = flag.String("rules", "", "autotile rules file, the embedded one by default")
+	TYPES_PATH = flag.String("types", "", "tile types file, the embedded one by default")
+)
+
+// Picks the variant of every grass and stone tile of the given map files and
+// saves them in place.
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: autotile [-rules file] [-types file] map.json...")
+		fmt.Fprintln(flag.CommandLine.Output(), "Maps are saved with layers, maps from before layers are migrated. Maps with nothing to change are left alone.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rules, err := tilemap.LoadAutoTileRules(assets.FS, tilemap.AutoTilePath)
+	if *RULES_PATH != "" {
+		rules, err = tilemap.LoadAutoTileRules(os.DirFS(filepath.Dir(*RULES_PATH)), filepath.Base(*RULES_PATH))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.AutoTiles = rules
+
+	// Tile types decide which layer the tiles of maps without layers go to.
+	tileTypes, err := tilemap.LoadTileTypes(assets.FS, tilemap.TileTypesPath)
+	if *TYPES_PATH != "" {
+		tileTypes, err = tilemap.LoadTileTypes(os.DirFS(filepath.Dir(*TYPES_PATH)), filepath.Base(*TYPES_PATH))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.TileTypes = tileTypes
+
+	for _, path := range flag.Args() {
+		tileMap := &tilemap.TileMapType{}
+		if err := tileMap.Load(path); err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+
+		changed := tileMap.AutoTile(tileMap.Bounds())
+		if changed == 0 {
+			log.Printf("%s: nothing to change", path)
+			continue
+		}
+		if err := tileMap.Save(path); err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+
+		log.Printf("%s: %d tiles changed", path, changed)
+	}
+}
@@ -48,22 +48,36 @@ func (c *CloudsType) Draw(screen *ebiten.Image, scrollX, scrollY int) {
 	}
 }
 
-func (c *CloudsType) GenerateRandomClouds() {
-	c.Clouds = randomClouds(c)
+// SetImages swaps the cloud images, each cloud keeping the variant it had.
+func (c *CloudsType) SetImages(images []*ebiten.Image) {
+	for i := range c.Clouds {
+		for variant, image := range c.CloudImages {
+			if c.Clouds[i].Image == image {
+				c.Clouds[i].Image = images[variant%len(images)]
+				break
+			}
+		}
+	}
+
+	c.CloudImages = images
+}
+
+func (c *CloudsType) GenerateRandomClouds(rng *rand.Rand) {
+	c.Clouds = randomClouds(c, rng)
 }
 
-func randomClouds(Clouds *CloudsType) []Cloud {
+func randomClouds(Clouds *CloudsType, rng *rand.Rand) []Cloud {
 	clouds := make([]Cloud, Clouds.Count)
 
 	for i := 0; i < Clouds.Count; i++ {
 		clouds[i] = Cloud{
 			Position: types.Vector{
-				X: rand.Float64() * 99999,
-				Y: rand.Float64() * 99999,
+				X: rng.Float64() * 99999,
+				Y: rng.Float64() * 99999,
 			},
-			Image: Clouds.CloudImages[rand.Intn(len(Clouds.CloudImages))],
-			Speed: rand.Float64()*0.05 + 0.05,
-			Depth: rand.Float64()*0.6 + 0.2,
+			Image: Clouds.CloudImages[rng.Intn(len(Clouds.CloudImages))],
+			Speed: rng.Float64()*0.05 + 0.05,
+			Depth: rng.Float64()*0.6 + 0.2,
 		}
 	}
 
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/rects"
@@ -12,7 +20,8 @@ import (
 var (
 	RENDER_SCALE   = 2
 	MOVEMENT_SPEED = 3
-	PATH           = "assets/data/maps/new_map.json"
+	PATH           = flag.String("map", "assets/data/maps/new_map.json", "map file to edit, relative to the working directory, not to -data; a missing file starts a new map")
+	DATA_PATH      = flag.String("data", "", "directory whose files override the embedded assets")
 )
 
 type Editor struct {
@@ -26,6 +35,7 @@ type Editor struct {
 	tileList      []string
 	tileGroup     int
 	tileVariant   int
+	layer         int
 	position      types.Vector
 }
 
@@ -43,6 +53,17 @@ func (e *Editor) Draw(screen *ebiten.Image) {
 	e.DrawCurrentTile(screen)
 }
 
+// CurrentLayer is the layer tiles are placed on and removed from.
+func (e *Editor) CurrentLayer() *tilemap.Layer {
+	return tilemap.TileMap.Layers[e.layer]
+}
+
+// layerScroll is the scroll of the current layer, which differs from the
+// editor's for parallax layers.
+func (e *Editor) layerScroll() (int, int) {
+	return e.CurrentLayer().Scroll(e.scrollX, e.scrollY)
+}
+
 func (e *Editor) CurrentTileImage() *ebiten.Image {
 	return assets.Assets.Images[e.tileList[e.tileGroup]].Image[e.tileVariant]
 }
@@ -54,15 +75,18 @@ func (e *Editor) DrawLayout(screen *ebiten.Image) {
 	options.ColorScale.ScaleAlpha(0.65)
 	options.GeoM.Translate(5, 5)
 	screen.DrawImage(currentTileImage, options)
+
+	ebitenutil.DebugPrintAt(screen, e.CurrentLayer().Name, 5, e.screenHeight-20)
 }
 
 func (e *Editor) DrawCurrentTile(screen *ebiten.Image) {
 	currentTileImage := e.CurrentTileImage()
 	options := &ebiten.DrawImageOptions{}
 	options.ColorScale.ScaleAlpha(0.65)
+	scrollX, scrollY := e.layerScroll()
 
 	if e.onGrid {
-		options.GeoM.Translate(float64((int(e.position.X)+e.scrollX)/tilemap.TileMap.TileSize)*float64(tilemap.TileMap.TileSize)-float64(e.scrollX), float64((int(e.position.Y)+e.scrollY)/tilemap.TileMap.TileSize)*float64(tilemap.TileMap.TileSize)-float64(e.scrollY))
+		options.GeoM.Translate(float64((int(e.position.X)+scrollX)/tilemap.TileMap.TileSize)*float64(tilemap.TileMap.TileSize)-float64(scrollX), float64((int(e.position.Y)+scrollY)/tilemap.TileMap.TileSize)*float64(tilemap.TileMap.TileSize)-float64(scrollY))
 	} else {
 		options.GeoM.Translate(e.position.X-float64(currentTileImage.Bounds().Dx()/2), e.position.Y-float64(currentTileImage.Bounds().Dy()/2))
 	}
@@ -83,7 +107,19 @@ func (e *Editor) HandleInputs() {
 		e.onGrid = !e.onGrid
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
-		tilemap.TileMap.Save(PATH)
+		if err := tilemap.TileMap.Save(*PATH); err != nil {
+			log.Println(err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		e.layer = (e.layer + 1) % len(tilemap.TileMap.Layers)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			tilemap.TileMap.AutoTile(tilemap.TileMap.Bounds())
+		} else {
+			tilemap.TileMap.AutoTile(e.VisibleRegion())
+		}
 	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyW) {
@@ -155,44 +191,60 @@ func (e *Editor) HandleInputs() {
 	}
 }
 
+// VisibleRegion is the part of the grid on screen, plus a tile around it so
+// edges get fixed too.
+func (e *Editor) VisibleRegion() tilemap.Region {
+	tileSize := tilemap.TileMap.TileSize
+	return tilemap.Region{
+		Left:   e.scrollX/tileSize - 1,
+		Top:    e.scrollY/tileSize - 1,
+		Right:  (e.scrollX+e.screenWidth)/tileSize + 1,
+		Bottom: (e.scrollY+e.screenHeight)/tileSize + 1,
+	}
+}
+
 func (e *Editor) RemoveTile() {
-	tilemap.TileMap.RemoveTile(types.Vector{X: float64((int(e.position.X) + e.scrollX) / tilemap.TileMap.TileSize), Y: float64((int(e.position.Y) + e.scrollY) / tilemap.TileMap.TileSize)})
+	scrollX, scrollY := e.layerScroll()
+	e.CurrentLayer().RemoveTile(types.Vector{X: float64((int(e.position.X) + scrollX) / tilemap.TileMap.TileSize), Y: float64((int(e.position.Y) + scrollY) / tilemap.TileMap.TileSize)})
 }
 
 func (e *Editor) RemoveOffGridTile() {
-	for _, tile := range tilemap.TileMap.OffGridTiles {
+	scrollX, scrollY := e.layerScroll()
+	for _, tile := range e.CurrentLayer().OffGridTiles {
 		tile_image := assets.Assets.Images[tile.Type].Image[tile.Variant]
 		tileRect := rects.Rect{
-			X:      tile.Position.X*float64(tilemap.TileMap.TileSize) - float64(e.scrollX),
-			Y:      tile.Position.Y*float64(tilemap.TileMap.TileSize) - float64(e.scrollY),
+			X:      tile.Position.X*float64(tilemap.TileMap.TileSize) - float64(scrollX),
+			Y:      tile.Position.Y*float64(tilemap.TileMap.TileSize) - float64(scrollY),
 			Width:  float64(tile_image.Bounds().Dx()),
 			Height: float64(tile_image.Bounds().Dy()),
 		}
 
 		if tileRect.Contains(e.position) {
-			tilemap.TileMap.RemoveOffGridTile(tile)
+			e.CurrentLayer().RemoveOffGridTile(tile)
 		}
 	}
 }
 
 func (e *Editor) AddTile() {
+	scrollX, scrollY := e.layerScroll()
 	tile := tilemap.Tile{
-		Position: types.Vector{X: float64((int(e.position.X) + e.scrollX) / tilemap.TileMap.TileSize), Y: float64((int(e.position.Y) + e.scrollY) / tilemap.TileMap.TileSize)},
+		Position: types.Vector{X: float64((int(e.position.X) + scrollX) / tilemap.TileMap.TileSize), Y: float64((int(e.position.Y) + scrollY) / tilemap.TileMap.TileSize)},
 		Variant:  e.tileVariant,
 		Type:     e.tileList[e.tileGroup],
 	}
 
-	tilemap.TileMap.SetTile(tile)
+	e.CurrentLayer().SetTile(tile)
 }
 
 func (e *Editor) AddOffgridTile() {
+	scrollX, scrollY := e.layerScroll()
 	tile := tilemap.Tile{
-		Position: types.Vector{X: (e.position.X + float64(e.scrollX) - float64(e.CurrentTileImage().Bounds().Dx()/2)) / float64(tilemap.TileMap.TileSize), Y: (e.position.Y + float64(e.scrollY) - float64(e.CurrentTileImage().Bounds().Dy()/2)) / float64(tilemap.TileMap.TileSize)},
+		Position: types.Vector{X: (e.position.X + float64(scrollX) - float64(e.CurrentTileImage().Bounds().Dx()/2)) / float64(tilemap.TileMap.TileSize), Y: (e.position.Y + float64(scrollY) - float64(e.CurrentTileImage().Bounds().Dy()/2)) / float64(tilemap.TileMap.TileSize)},
 		Variant:  e.tileVariant,
 		Type:     e.tileList[e.tileGroup],
 	}
 
-	tilemap.TileMap.SetOffGridTile(tile)
+	e.CurrentLayer().SetOffGridTile(tile)
 }
 
 func (e *Editor) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -208,11 +260,50 @@ func NewEditor() *Editor {
 }
 
 func main() {
+	flag.Parse()
+
+	if *DATA_PATH != "" {
+		assets.UseOverride(*DATA_PATH)
+	}
+	if err := assets.Load(assets.FS); err != nil {
+		log.Fatal(err)
+	}
+
+	rules, err := tilemap.LoadAutoTileRules(assets.FS, tilemap.AutoTilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.AutoTiles = rules
+
+	tileTypes, err := tilemap.LoadTileTypes(assets.FS, tilemap.TileTypesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.TileTypes = tileTypes
+
+	// Saving with O writes next to the map, check the directory is there
+	// before any editing is lost to a failed save.
+	if _, err := os.Stat(filepath.Dir(*PATH)); err != nil {
+		log.Fatalf("%s: %v, run the editor from the repository root or pass -map", *PATH, err)
+	}
+
 	editor := NewEditor()
 
-	tilemap.TileMap.Load(PATH)
+	// Only a missing file starts an empty map, anything else would be
+	// overwritten with one on the next save.
+	if err := tilemap.TileMap.Load(*PATH); errors.Is(err, fs.ErrNotExist) {
+		log.Printf("%s: new map", *PATH)
+	} else if err != nil {
+		log.Fatalf("%s: %v", *PATH, err)
+	}
+	if err := tilemap.TileMap.Validate(); err != nil {
+		log.Fatalf("%s: %v", *PATH, err)
+	}
 
-	editor.tileList = []string{"grass", "stone", "decor", "large_decor", "spawners"}
+	editor.tileList = assets.Assets.Tiles()
+	if len(editor.tileList) == 0 {
+		log.Fatalf("%s: no image group is marked as a tile", assets.ManifestPath)
+	}
 
 	ebiten.SetCursorMode(ebiten.CursorModeHidden)
 	ebiten.SetWindowSize(640, 480)
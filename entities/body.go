@@ -0,0 +1,204 @@
+package entities
+
+import (
+	"math"
+
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/rects"
+	"github.com/yuricorredor/platformer/tilemap"
+	"github.com/yuricorredor/platformer/types"
+)
+
+const (
+	Gravity          = 0.1
+	TerminalVelocity = 3
+)
+
+// Body is the kinematic part shared by every physics entity: it moves an
+// axis-aligned box through the tilemap and records which sides it touched.
+// Ground holds the properties of the tile it stands on, the default ones in
+// the air, and Hazard the most damage of the tiles it touches.
+type Body struct {
+	Position   types.Vector
+	Velocity   types.Vector
+	Collisions types.Collisions
+	Width      float64
+	Height     float64
+	Ground     tilemap.TileProperties
+	Hazard     int
+}
+
+func NewBody(position types.Vector, width, height float64) Body {
+	return Body{
+		Position: position,
+		Width:    width,
+		Height:   height,
+		Ground:   tilemap.DefaultTileProperties(),
+	}
+}
+
+// bodyForAsset sizes a body after the first image of the given asset.
+func bodyForAsset(position types.Vector, assetType string) Body {
+	bounds := assets.Assets.Images[assetType].Image[0].Bounds()
+	return NewBody(position, float64(bounds.Max.X), float64(bounds.Max.Y))
+}
+
+func (b *Body) Size() (int, int) {
+	return int(b.Width), int(b.Height)
+}
+
+func (b *Body) Rect() rects.Rect {
+	return rects.Rect{X: b.Position.X, Y: b.Position.Y, Width: b.Width, Height: b.Height}
+}
+
+// Center is where sounds made by the body come from.
+func (b *Body) Center() types.Vector {
+	return types.Vector{X: b.Position.X + b.Width/2, Y: b.Position.Y + b.Height/2}
+}
+
+func (b *Body) ResetCollisions() {
+	b.Collisions = types.Collisions{}
+	b.Ground = tilemap.DefaultTileProperties()
+	b.Hazard = 0
+}
+
+// MoveAndCollide moves the body by movement plus its own velocity, resolving
+// the X axis first and then the Y axis against the solid tiles it crosses.
+// Each axis is swept in sub-steps no longer than half the body, so fast
+// bodies cannot skip over a tile between two frames. One-way platforms only
+// stop the body when it falls onto them from above.
+func (b *Body) MoveAndCollide(tileMap *tilemap.TileMapType, movement types.Vector) types.Collisions {
+	b.ResetCollisions()
+
+	frameMovement := types.Vector{X: movement.X + b.Velocity.X, Y: movement.Y + b.Velocity.Y}
+	maxStep := math.Min(math.Min(b.Width, b.Height), float64(tileMap.TileSize)) / 2
+
+	b.sweepX(tileMap, frameMovement.X, maxStep)
+	b.sweepY(tileMap, frameMovement.Y, maxStep)
+	b.probeGround(tileMap)
+	b.touchHazards(tileMap)
+
+	return b.Collisions
+}
+
+func (b *Body) sweepX(tileMap *tilemap.TileMapType, distance, maxStep float64) {
+	steps := sweepSteps(distance, maxStep)
+	for i := 0; i < steps; i++ {
+		b.Position.X += distance / float64(steps)
+
+		hit := false
+		entityRect := b.Rect()
+		for _, rect := range tileMap.PhysicsRectsInRect(entityRect) {
+			if entityRect.Colliderect(rect) {
+				if distance > 0 {
+					entityRect.SetRight(rect.Left())
+					b.Collisions.Right = true
+				}
+				if distance < 0 {
+					entityRect.SetLeft(rect.Right())
+					b.Collisions.Left = true
+				}
+				b.Position.X = entityRect.X
+				hit = true
+			}
+		}
+
+		if hit {
+			return
+		}
+	}
+}
+
+func (b *Body) sweepY(tileMap *tilemap.TileMapType, distance, maxStep float64) {
+	steps := sweepSteps(distance, maxStep)
+	for i := 0; i < steps; i++ {
+		previousBottom := b.Position.Y + b.Height
+		b.Position.Y += distance / float64(steps)
+
+		hit := false
+		entityRect := b.Rect()
+		for _, tile := range tileMap.PhysicsTilesInRect(entityRect) {
+			rect := tile.Rect
+			if !entityRect.Colliderect(rect) {
+				continue
+			}
+			if !tile.Properties.Solid && !(tile.Properties.OneWay && distance > 0 && previousBottom <= rect.Top()) {
+				continue
+			}
+
+			if distance > 0 {
+				entityRect.SetBottom(rect.Top())
+				b.Collisions.Bottom = true
+				b.Ground = tile.Properties
+			}
+			if distance < 0 {
+				entityRect.SetTop(rect.Bottom())
+				b.Collisions.Top = true
+			}
+			b.Position.Y = entityRect.Y
+			hit = true
+		}
+
+		if hit {
+			return
+		}
+	}
+}
+
+// probeGround keeps the ground of a body resting on it. Gravity only pushes
+// such a body into the floor every other frame, so the sweep alone would
+// leave it in the air, with the default properties, half of the time.
+func (b *Body) probeGround(tileMap *tilemap.TileMapType) {
+	if b.Collisions.Bottom || b.Velocity.Y < 0 {
+		return
+	}
+
+	feet := rects.Rect{X: b.Position.X, Y: b.Position.Y + b.Height, Width: b.Width, Height: 1}
+	for _, tile := range tileMap.PhysicsTilesInRect(feet) {
+		if !tile.Properties.Solid && !tile.Properties.OneWay {
+			continue
+		}
+		if tile.Rect.Top() >= feet.Top() && feet.Colliderect(tile.Rect) {
+			b.Ground = tile.Properties
+			return
+		}
+	}
+}
+
+// touchHazards records the damage of the tiles overlapping or next to the
+// body, so that spikes hurt whether they are solid or not.
+func (b *Body) touchHazards(tileMap *tilemap.TileMapType) {
+	area := b.Rect()
+	area.X--
+	area.Y--
+	area.Width += 2
+	area.Height += 2
+
+	for _, tile := range tileMap.PhysicsTilesInRect(area) {
+		if tile.Properties.Damage > b.Hazard && area.Colliderect(tile.Rect) {
+			b.Hazard = tile.Properties.Damage
+		}
+	}
+}
+
+func sweepSteps(distance, maxStep float64) int {
+	if maxStep <= 0 {
+		return 1
+	}
+
+	return int(math.Max(1, math.Ceil(math.Abs(distance)/maxStep)))
+}
+
+// ApplyGravity accelerates the body downwards up to the terminal velocity and
+// stops vertical motion when the last move hit a floor or a ceiling, unless
+// the floor is bouncy and launches the body back up.
+func (b *Body) ApplyGravity(gravity, terminalVelocity float64) {
+	b.Velocity.Y = math.Min(terminalVelocity, b.Velocity.Y+gravity)
+
+	if b.Collisions.Bottom || b.Collisions.Top {
+		b.Velocity.Y = 0
+	}
+	if b.Collisions.Bottom && b.Ground.Bounce > 0 {
+		b.Velocity.Y = -b.Ground.Bounce
+	}
+}
@@ -0,0 +1,160 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/yuricorredor/platformer/tilemap"
+	"github.com/yuricorredor/platformer/types"
+)
+
+const testTileSize = 16
+
+var testTileTypes = tilemap.TileTypeRegistry{
+	{Type: "stone", Variant: tilemap.AnyVariant}:    {Solid: true, Friction: 1},
+	{Type: "platform", Variant: tilemap.AnyVariant}: {OneWay: true, Friction: 1},
+	{Type: "spikes", Variant: tilemap.AnyVariant}:   {Damage: 2, Friction: 1},
+	{Type: "ice", Variant: tilemap.AnyVariant}:      {Solid: true, Friction: 0.05},
+}
+
+// testTileMap puts a row of the given tile type at y = 5, from x = 0 to 9.
+func testTileMap(t *testing.T, tileType string) *tilemap.TileMapType {
+	t.Helper()
+
+	saved := tilemap.TileTypes
+	tilemap.TileTypes = testTileTypes
+	t.Cleanup(func() { tilemap.TileTypes = saved })
+
+	tileMap := &tilemap.TileMapType{TileSize: testTileSize, Layers: tilemap.DefaultLayers()}
+	for x := 0; x < 10; x++ {
+		tileMap.Layer("solid").SetTile(tilemap.Tile{Position: types.Vector{X: float64(x), Y: 5}, Type: tileType})
+	}
+
+	return tileMap
+}
+
+func TestSweepYOneWay(t *testing.T) {
+	const top = 5 * testTileSize
+
+	tests := []struct {
+		name     string
+		y        float64
+		velocity float64
+		landed   bool
+		wantY    float64
+	}{
+		{name: "falling from above", y: top - 10 - 2, velocity: 3, landed: true, wantY: top - 10},
+		{name: "fast fall from above", y: top - 10 - 20, velocity: 30, landed: true, wantY: top - 10},
+		{name: "jumping from below", y: top + testTileSize + 2, velocity: -8, wantY: top + testTileSize + 2 - 8},
+		{name: "falling while inside", y: top - 5, velocity: 3, wantY: top - 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tileMap := testTileMap(t, "platform")
+			body := NewBody(types.Vector{X: 20, Y: test.y}, 8, 10)
+			body.Velocity.Y = test.velocity
+
+			collisions := body.MoveAndCollide(tileMap, types.Vector{})
+
+			if collisions.Bottom != test.landed {
+				t.Errorf("landed = %v, want %v", collisions.Bottom, test.landed)
+			}
+			if collisions.Top {
+				t.Errorf("hit the platform from below")
+			}
+			if body.Position.Y != test.wantY {
+				t.Errorf("y = %v, want %v", body.Position.Y, test.wantY)
+			}
+		})
+	}
+}
+
+func TestHazard(t *testing.T) {
+	const top = 5 * testTileSize
+
+	tests := []struct {
+		name     string
+		position types.Vector
+		want     int
+	}{
+		{name: "standing on spikes", position: types.Vector{X: 20, Y: top - 10}, want: 2},
+		{name: "inside spikes", position: types.Vector{X: 20, Y: top + 2}, want: 2},
+		{name: "next to spikes", position: types.Vector{X: 10*testTileSize + 0.5, Y: top}, want: 2},
+		{name: "above spikes", position: types.Vector{X: 20, Y: top - 20}, want: 0},
+		{name: "away from spikes", position: types.Vector{X: 12 * testTileSize, Y: top}, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tileMap := testTileMap(t, "spikes")
+			body := NewBody(test.position, 8, 10)
+
+			body.MoveAndCollide(tileMap, types.Vector{})
+
+			if body.Hazard != test.want {
+				t.Errorf("hazard = %d, want %d", body.Hazard, test.want)
+			}
+		})
+	}
+}
+
+func TestGroundKeptWhileResting(t *testing.T) {
+	tileMap := testTileMap(t, "ice")
+	body := NewBody(types.Vector{X: 20, Y: 5*testTileSize - 10}, 8, 10)
+
+	for frame := 0; frame < 10; frame++ {
+		body.MoveAndCollide(tileMap, types.Vector{})
+		body.ApplyGravity(Gravity, TerminalVelocity)
+
+		if body.Ground.Friction != 0.05 {
+			t.Fatalf("frame %d: ground friction = %v, want 0.05", frame, body.Ground.Friction)
+		}
+	}
+
+	body.Position.Y -= 5
+	body.Velocity.Y = 0
+	body.MoveAndCollide(tileMap, types.Vector{})
+	if body.Ground != tilemap.DefaultTileProperties() {
+		t.Errorf("ground in the air = %+v, want the default", body.Ground)
+	}
+}
+
+func TestSweepXStopsAtThinWall(t *testing.T) {
+	saved := tilemap.TileTypes
+	tilemap.TileTypes = testTileTypes
+	t.Cleanup(func() { tilemap.TileTypes = saved })
+
+	// A wall one tile thick, from x = 80 to 96.
+	tileMap := &tilemap.TileMapType{TileSize: testTileSize, Layers: tilemap.DefaultLayers()}
+	for y := 0; y < 10; y++ {
+		tileMap.Layer("solid").SetTile(tilemap.Tile{Position: types.Vector{X: 5, Y: float64(y)}, Type: "stone"})
+	}
+
+	tests := []struct {
+		name     string
+		x        float64
+		velocity float64
+		wantX    float64
+	}{
+		{name: "dash speed", x: 70, velocity: DefaultPlayerConfig().DashSpeed, wantX: 80 - 8},
+		{name: "right", x: 60, velocity: 30, wantX: 80 - 8},
+		{name: "left", x: 110, velocity: -30, wantX: 96},
+		{name: "faster than the wall is thick", x: 40, velocity: 60, wantX: 80 - 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := NewBody(types.Vector{X: test.x, Y: 40}, 8, 10)
+			body.Velocity.X = test.velocity
+
+			collisions := body.MoveAndCollide(tileMap, types.Vector{})
+
+			if body.Position.X != test.wantX {
+				t.Errorf("x = %v, want %v", body.Position.X, test.wantX)
+			}
+			if !collisions.Left && !collisions.Right {
+				t.Error("no wall collision")
+			}
+		})
+	}
+}
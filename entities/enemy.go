@@ -2,45 +2,33 @@ package entities
 
 import (
 	"math"
-	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/particle"
-	"github.com/yuricorredor/platformer/rects"
-	"github.com/yuricorredor/platformer/tilemap"
 	"github.com/yuricorredor/platformer/types"
 )
 
 type EnemyEntity struct {
+	Body
 	EntityType string
-	Position   types.Vector
-	Velocity   types.Vector
 	Walking    int
 	Flipped    bool
-	Collisions types.Collisions
 	Action     string
-	Animations map[string]*animation.Animation
-}
-
-func (enemy *EnemyEntity) Size() (int, int) {
-	bounds := assets.Assets.Images[enemy.EntityType].Image[0].Bounds()
-	return bounds.Max.X, bounds.Max.Y
-}
-
-func (enemy *EnemyEntity) Rect() rects.Rect {
-	width, height := enemy.Size()
-	return rects.Rect{X: enemy.Position.X, Y: enemy.Position.Y, Width: float64(width), Height: float64(height)}
+	Animator   *animation.Animator
+	Machine    *animation.StateMachine[*Scene]
+	Movement   types.Vector
 }
 
 func (enemy *EnemyEntity) SetAction(action string) {
 	enemy.Action = action
+	enemy.Animator.Play(action)
 }
 
 func (enemy *EnemyEntity) Draw(screen *ebiten.Image, scrollX, scrollY int) {
-	image := enemy.Animations[enemy.Action].Image()
-	imageOffset := enemy.Animations[enemy.Action].Offset
+	image := enemy.Animator.Image()
+	imageOffset := enemy.Animator.Offset()
 	options := &ebiten.DrawImageOptions{}
 	if enemy.Flipped {
 		options.GeoM.Scale(-1, 1)
@@ -61,16 +49,7 @@ func (enemy *EnemyEntity) Draw(screen *ebiten.Image, scrollX, scrollY int) {
 	screen.DrawImage(gunImage, options)
 }
 
-func (enemy *EnemyEntity) ResetCollisions() {
-	enemy.Collisions.Top = false
-	enemy.Collisions.Bottom = false
-	enemy.Collisions.Left = false
-	enemy.Collisions.Right = false
-}
-
-func (enemy *EnemyEntity) Update() error {
-	enemy.Animations[enemy.Action].Update()
-
+func (enemy *EnemyEntity) Update(scene *Scene) error {
 	var movement = types.Vector{X: 0, Y: 0}
 
 	if enemy.Walking != 0 {
@@ -82,7 +61,7 @@ func (enemy *EnemyEntity) Update() error {
 			positionToCheck.X += 7
 		}
 
-		if tilemap.TileMap.CheckForSolid(positionToCheck) {
+		if scene.TileMap.CheckForFloor(positionToCheck) {
 			if enemy.Collisions.Right || enemy.Collisions.Left {
 				enemy.Flipped = !enemy.Flipped
 			} else {
@@ -106,8 +85,8 @@ func (enemy *EnemyEntity) Update() error {
 
 		if enemy.Walking == 0 {
 			distanceEnemyPlayer := &types.Vector{
-				X: Player.Position.X - enemy.Position.X,
-				Y: Player.Position.Y - enemy.Position.Y,
+				X: scene.Player.Position.X - enemy.Position.X,
+				Y: scene.Player.Position.Y - enemy.Position.Y,
 			}
 
 			if math.Abs(distanceEnemyPlayer.Y) < 16 {
@@ -121,64 +100,29 @@ func (enemy *EnemyEntity) Update() error {
 				}
 				if enemy.Flipped && distanceEnemyPlayer.X < 0 {
 					projectileVelocity.X = -1.5
-					particle.Projectiles.Particles = append(particle.Projectiles.Particles, particle.NewProjectile(projectilePosition, projectileVelocity))
+					scene.Projectiles.Particles = append(scene.Projectiles.Particles, particle.NewProjectile(projectilePosition, projectileVelocity))
+					scene.Audio.PlayAt("shoot", enemy.Center())
 					for i := 0; i < 4; i++ {
-						angle := rand.Float64() * math.Pi * 2
-						particle.SparksParticles.Particles = append(particle.SparksParticles.Particles, particle.NewSpark(angle, projectilePosition, types.Vector{X: 1, Y: 1}))
+						angle := scene.Rand.Float64() * math.Pi * 2
+						scene.Sparks.Particles = append(scene.Sparks.Particles, particle.NewSpark(angle, projectilePosition, types.Vector{X: 1, Y: 1}))
 					}
 				} else if !enemy.Flipped && distanceEnemyPlayer.X > 0 {
 					projectileVelocity.X = 1.5
-					particle.Projectiles.Particles = append(particle.Projectiles.Particles, particle.NewProjectile(projectilePosition, projectileVelocity))
+					scene.Projectiles.Particles = append(scene.Projectiles.Particles, particle.NewProjectile(projectilePosition, projectileVelocity))
+					scene.Audio.PlayAt("shoot", enemy.Center())
 					for i := 0; i < 4; i++ {
-						angle := rand.Float64() * math.Pi
-						particle.SparksParticles.Particles = append(particle.SparksParticles.Particles, particle.NewSpark(angle, projectilePosition, types.Vector{X: 1, Y: 1}))
+						angle := scene.Rand.Float64() * math.Pi
+						scene.Sparks.Particles = append(scene.Sparks.Particles, particle.NewSpark(angle, projectilePosition, types.Vector{X: 1, Y: 1}))
 					}
 				}
 			}
 		}
 
-	} else if rand.Intn(100) == 1 {
-		enemy.Walking = rand.Intn(120)
+	} else if scene.Rand.Intn(100) == 1 {
+		enemy.Walking = scene.Rand.Intn(120)
 	}
 
-	enemy.ResetCollisions()
-
-	frameMovement := types.Vector{X: movement.X + enemy.Velocity.X, Y: movement.Y + enemy.Velocity.Y}
-
-	enemy.Position.X += frameMovement.X
-	entityRect := enemy.Rect()
-	rectsList := tilemap.TileMap.PhysicsRectsAroundPosition(enemy.Position)
-
-	for _, rect := range rectsList {
-		if entityRect.Colliderect(rect) {
-			if frameMovement.X > 0 {
-				entityRect.SetRight(rect.Left())
-				enemy.Collisions.Right = true
-			}
-			if frameMovement.X < 0 {
-				entityRect.SetLeft(rect.Right())
-				enemy.Collisions.Left = true
-			}
-			enemy.Position.X = entityRect.X
-		}
-	}
-
-	enemy.Position.Y += frameMovement.Y
-	entityRect = enemy.Rect()
-	rectsList = tilemap.TileMap.PhysicsRectsAroundPosition(enemy.Position)
-	for _, rect := range rectsList {
-		if entityRect.Colliderect(rect) {
-			if frameMovement.Y > 0 {
-				entityRect.SetBottom(rect.Top())
-				enemy.Collisions.Bottom = true
-			}
-			if frameMovement.Y < 0 {
-				entityRect.SetTop(rect.Bottom())
-				enemy.Collisions.Top = true
-			}
-			enemy.Position.Y = entityRect.Y
-		}
-	}
+	enemy.MoveAndCollide(scene.TileMap, movement)
 
 	if movement.X > 0 {
 		enemy.Flipped = false
@@ -187,53 +131,42 @@ func (enemy *EnemyEntity) Update() error {
 		enemy.Flipped = true
 	}
 
-	enemy.Velocity.Y = math.Min(3, enemy.Velocity.Y+0.1)
+	enemy.ApplyGravity(Gravity, TerminalVelocity)
 
-	if enemy.Collisions.Bottom || enemy.Collisions.Top {
-		enemy.Velocity.Y = 0
-	}
-
-	if movement.X != 0 {
-		enemy.SetAction("run")
-	} else {
-		enemy.SetAction("idle")
-	}
+	enemy.Movement = movement
+	enemy.Machine.Update(scene)
+	enemy.Action = enemy.Machine.State()
 
 	return nil
 }
 
 func CreateEnemy(position types.Vector) *EnemyEntity {
-	return &EnemyEntity{
+	enemy := &EnemyEntity{
+		Body:       bodyForAsset(position, "enemy"),
 		EntityType: "enemy",
-		Position:   position,
-		Velocity:   types.Vector{X: 0, Y: 0},
 		Walking:    0,
 		Flipped:    false,
-		Collisions: types.Collisions{},
 		Action:     "idle",
-		Animations: EnemyAnimations,
+		Animator:   animation.NewAnimator(EnemyClips(), "idle"),
 	}
+	enemy.Machine = newEnemyStateMachine(enemy)
+
+	return enemy
 }
 
-var EnemyAnimations = map[string]*animation.Animation{
-	"idle": {
-		Images:        assets.Assets.Images["enemy_idle"].Image,
-		ImageDuration: 8,
-		Loop:          true,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
-	"run": {
-		Images:        assets.Assets.Images["enemy_run"].Image,
-		ImageDuration: 4,
-		Loop:          true,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
+func newEnemyStateMachine(enemy *EnemyEntity) *animation.StateMachine[*Scene] {
+	machine := animation.NewStateMachine[*Scene](enemy.Animator)
+
+	machine.AddTransition("", "run", 1, func(scene *Scene) bool { return enemy.Movement.X != 0 })
+	machine.AddTransition("", "idle", 0, func(scene *Scene) bool { return true })
+
+	return machine
+}
+
+// EnemyClipsPrefix names the image groups of the enemy animations, the rest
+// of the name being the state, as in "enemy_run".
+const EnemyClipsPrefix = "enemy_"
+
+func EnemyClips() map[string]*animation.Clip {
+	return assets.Assets.Clips(EnemyClipsPrefix)
 }
@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// InputSource reports the player actions for the current frame.
+type InputSource interface {
+	MoveLeft() bool
+	MoveRight() bool
+	JumpPressed() bool
+	JumpHeld() bool
+	DashPressed() bool
+}
+
+// Input is a fixed set of actions, handy for scripted and recorded frames. A
+// pressed Jump also counts as held, so it isn't cut on its first frame.
+type Input struct {
+	Left     bool
+	Right    bool
+	Jump     bool
+	Dash     bool
+	HoldJump bool
+}
+
+func (i Input) MoveLeft() bool    { return i.Left }
+func (i Input) MoveRight() bool   { return i.Right }
+func (i Input) JumpPressed() bool { return i.Jump }
+func (i Input) JumpHeld() bool    { return i.Jump || i.HoldJump }
+func (i Input) DashPressed() bool { return i.Dash }
+
+type KeyboardInput struct{}
+
+func (k KeyboardInput) MoveLeft() bool    { return ebiten.IsKeyPressed(ebiten.KeyA) }
+func (k KeyboardInput) MoveRight() bool   { return ebiten.IsKeyPressed(ebiten.KeyD) }
+func (k KeyboardInput) JumpPressed() bool { return inpututil.IsKeyJustPressed(ebiten.KeySpace) }
+func (k KeyboardInput) JumpHeld() bool    { return ebiten.IsKeyPressed(ebiten.KeySpace) }
+func (k KeyboardInput) DashPressed() bool { return inpututil.IsKeyJustPressed(ebiten.KeyShift) }
@@ -6,7 +6,6 @@ import (
 )
 
 type PhysicsEntity interface {
-	Update() error
 	Draw(screen *ebiten.Image, scrollX, scrollY int)
 	SetAction(action string)
 	Size() (int, int)
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"encoding/json"
+	"io/fs"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+// PlayerConfigPath is relative to assets.FS.
+const PlayerConfigPath = "player.json"
+
+// PlayerConfig holds the numbers that define how the player moves. Fields left
+// out of the JSON file keep their default value. CoyoteFrames is how long after
+// leaving a ledge or a wall a jump is still accepted, JumpBufferFrames how long
+// an early jump press is remembered, and JumpCutMultiplier scales the upward
+// velocity when jump is released before the top of the jump. HurtFrames is
+// how long the player cannot be damaged again after a hit.
+type PlayerConfig struct {
+	JumpVelocity      float64
+	WallJumpVelocity  types.Vector
+	DashFrames        int
+	DashActiveFrames  int
+	DashSpeed         float64
+	Gravity           float64
+	TerminalVelocity  float64
+	Friction          float64
+	WallSlideSpeed    float64
+	CoyoteFrames      int
+	JumpBufferFrames  int
+	JumpCutMultiplier float64
+	MaxHealth         int
+	HurtFrames        int
+}
+
+func DefaultPlayerConfig() *PlayerConfig {
+	return &PlayerConfig{
+		JumpVelocity:      3,
+		WallJumpVelocity:  types.Vector{X: 3.5, Y: 2.5},
+		DashFrames:        60,
+		DashActiveFrames:  10,
+		DashSpeed:         8,
+		Gravity:           Gravity,
+		TerminalVelocity:  TerminalVelocity,
+		Friction:          0.1,
+		WallSlideSpeed:    0.5,
+		CoyoteFrames:      6,
+		JumpBufferFrames:  6,
+		JumpCutMultiplier: 0.5,
+		MaxHealth:         3,
+		HurtFrames:        60,
+	}
+}
+
+func LoadPlayerConfig(fsys fs.FS, path string) (*PlayerConfig, error) {
+	config := DefaultPlayerConfig()
+	if err := config.Reload(fsys, path); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Reload replaces the config in place, so every player holding it picks the
+// new values up on its next update.
+func (c *PlayerConfig) Reload(fsys fs.FS, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config := DefaultPlayerConfig()
+	if err := json.NewDecoder(f).Decode(config); err != nil {
+		return err
+	}
+
+	*c = *config
+	return nil
+}
@@ -2,38 +2,47 @@ package entities
 
 import (
 	"math"
-	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/particle"
-	"github.com/yuricorredor/platformer/rects"
-	"github.com/yuricorredor/platformer/tilemap"
 	"github.com/yuricorredor/platformer/types"
 )
 
 type PlayerEntity struct {
+	Body
 	EntityType string
-	Position   types.Vector
-	Velocity   types.Vector
-	Collisions types.Collisions
 	Action     string
-	Animations map[string]*animation.Animation
+	Animator   *animation.Animator
+	Machine    *animation.StateMachine[*Scene]
+	Movement   types.Vector
 	Flipped    bool
 	AirTime    int
 	Jumps      int
 	WallSlide  bool
 	Dashing    float64
+	Config     *PlayerConfig
+	// JumpBuffer counts down the frames an early jump press stays valid,
+	// WallCoyote the frames a wall jump stays valid after leaving the wall.
+	JumpBuffer  int
+	WallCoyote  int
+	WallFlipped bool
+	Jumping     bool
+	Health      int
+	Hurt        int
+	Dead        bool
 }
 
 func (p *PlayerEntity) Draw(screen *ebiten.Image, scrollX, scrollY int) {
-	if math.Abs(p.Dashing) > 50 {
+	if p.IsDashing() || p.Dead {
 		return
 	}
-	image := p.Animations[p.Action].Image()
-	imageOffset := p.Animations[p.Action].Offset
+	if p.Hurt > 0 && p.Hurt/4%2 == 0 {
+		return
+	}
+	image := p.Animator.Image()
+	imageOffset := p.Animator.Offset()
 	options := &ebiten.DrawImageOptions{}
 	if p.Flipped {
 		options.GeoM.Scale(-1, 1)
@@ -45,117 +54,154 @@ func (p *PlayerEntity) Draw(screen *ebiten.Image, scrollX, scrollY int) {
 
 func (p *PlayerEntity) SetAction(action string) {
 	p.Action = action
+	p.Animator.Play(action)
 }
 
-func (p *PlayerEntity) Jump() bool {
+func (p *PlayerEntity) Jump(input InputSource) bool {
 	var jumped = false
 
-	if p.WallSlide {
-		if p.Flipped && ebiten.IsKeyPressed(ebiten.KeyA) {
-			p.Velocity.X = 3.5
-			p.Velocity.Y = -2.5
+	if p.WallSlide || p.WallCoyote > 0 {
+		if p.WallFlipped && input.MoveLeft() {
+			p.Velocity.X = p.Config.WallJumpVelocity.X
+			p.Velocity.Y = -p.Config.WallJumpVelocity.Y
 			p.AirTime = 5
 			p.Jumps = int(math.Max(0, float64(p.Jumps-1)))
 			jumped = true
-		} else if !p.Flipped && ebiten.IsKeyPressed(ebiten.KeyD) {
-			p.Velocity.X = -3.5
-			p.Velocity.Y = -2.5
+		} else if !p.WallFlipped && input.MoveRight() {
+			p.Velocity.X = -p.Config.WallJumpVelocity.X
+			p.Velocity.Y = -p.Config.WallJumpVelocity.Y
 			p.AirTime = 5
 			p.Jumps = int(math.Max(0, float64(p.Jumps-1)))
 			jumped = true
 		}
-	} else if p.Jumps != 0 {
-		p.Velocity.Y = -3
+	}
+
+	if !jumped && !p.WallSlide && p.Jumps != 0 && p.AirTime <= p.Config.CoyoteFrames {
+		p.Velocity.Y = -p.Config.JumpVelocity
 		p.Jumps--
 		p.AirTime = 5
 		jumped = true
 	}
 
+	if jumped {
+		p.WallCoyote = 0
+		p.Jumping = true
+	}
+
 	return jumped
 }
 
-func (p *PlayerEntity) Dash() {
-	if p.Dashing == 0 {
-		if p.Flipped {
-			p.Dashing = -60
-		} else {
-			p.Dashing = 60
-		}
+// Dash starts a dash unless the last one is still recharging, and reports
+// whether it did.
+func (p *PlayerEntity) Dash() bool {
+	if p.Dashing != 0 {
+		return false
 	}
+
+	if p.Flipped {
+		p.Dashing = -float64(p.Config.DashFrames)
+	} else {
+		p.Dashing = float64(p.Config.DashFrames)
+	}
+
+	return true
 }
 
-func (p *PlayerEntity) ResetCollisions() {
-	p.Collisions.Top = false
-	p.Collisions.Bottom = false
-	p.Collisions.Left = false
-	p.Collisions.Right = false
+// IsDashing reports whether the player is in the fast part of a dash, as
+// opposed to waiting for the dash to recharge.
+func (p *PlayerEntity) IsDashing() bool {
+	return math.Abs(p.Dashing) > p.dashCooldown()
 }
 
-func (p *PlayerEntity) Size() (int, int) {
-	bounds := assets.Assets.Images[p.EntityType].Image[0].Bounds()
-	return bounds.Max.X, bounds.Max.Y
+func (p *PlayerEntity) dashCooldown() float64 {
+	return float64(p.Config.DashFrames - p.Config.DashActiveFrames)
 }
 
-func (p *PlayerEntity) Rect() rects.Rect {
-	width, height := p.Size()
-	return rects.Rect{X: p.Position.X, Y: p.Position.Y, Width: float64(width), Height: float64(height)}
+// Damage takes health away from the player unless it was hurt recently, and
+// reports whether the hit killed it.
+func (p *PlayerEntity) Damage(amount int, scene *Scene) bool {
+	if p.Dead || p.Hurt > 0 || amount <= 0 {
+		return false
+	}
+
+	rect := p.Rect()
+	position := types.Vector{X: rect.CenterX(), Y: rect.CenterY()}
+
+	p.Health = int(math.Max(0, float64(p.Health-amount)))
+	p.Hurt = p.Config.HurtFrames
+	scene.Audio.PlayAt("hit", position)
+
+	if p.Health > 0 {
+		for i := 0; i < 8; i++ {
+			angle := scene.Rand.Float64() * math.Pi * 2
+			scene.Sparks.Particles = append(scene.Sparks.Particles, particle.NewSpark(angle, position, types.Vector{X: 1.5, Y: 1.5}))
+		}
+		return false
+	}
+
+	p.Dead = true
+	for i := 0; i < 30; i++ {
+		angle := scene.Rand.Float64() * math.Pi * 2
+		speed := scene.Rand.Float64() * 5
+		scene.Sparks.Particles = append(scene.Sparks.Particles, particle.NewSpark(angle, position, types.Vector{X: speed + 2, Y: speed + 2}))
+
+		velocity := types.Vector{
+			X: math.Cos(angle+math.Pi) * speed * 0.5,
+			Y: math.Sin(angle+math.Pi) * speed * 0.5,
+		}
+		scene.DashParticles.Particles =
+			append(scene.DashParticles.Particles, particle.CreateDashParticle(scene.Rand, velocity, position))
+	}
+
+	return true
 }
 
-func (p *PlayerEntity) Update() error {
-	p.Animations[p.Action].Update()
+func (p *PlayerEntity) Update(input InputSource, scene *Scene) error {
+	if p.Dead {
+		return nil
+	}
+
+	if p.Hurt > 0 {
+		p.Hurt--
+	}
 
-	p.ResetCollisions()
 	var movement = types.Vector{X: 0, Y: 0}
 
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
+	if input.MoveLeft() {
 		movement.X -= 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) {
+	if input.MoveRight() {
 		movement.X += 1
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		p.Jump()
+	if input.JumpPressed() {
+		p.JumpBuffer = p.Config.JumpBufferFrames + 1
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyShift) {
-		p.Dash()
-	}
-
-	frameMovement := types.Vector{X: movement.X + p.Velocity.X, Y: movement.Y + p.Velocity.Y}
-
-	p.Position.X += frameMovement.X
-	rectsList := tilemap.TileMap.PhysicsRectsAroundPosition(p.Position)
-	entityRect := p.Rect()
-	for _, rect := range rectsList {
-		if entityRect.Colliderect(rect) {
-			if frameMovement.X > 0 {
-				entityRect.SetRight(rect.Left())
-				p.Collisions.Right = true
-			}
-			if frameMovement.X < 0 {
-				entityRect.SetLeft(rect.Right())
-				p.Collisions.Left = true
-			}
-			p.Position.X = entityRect.X
+	if p.JumpBuffer > 0 {
+		p.JumpBuffer--
+		if p.Jump(input) {
+			p.JumpBuffer = 0
+			scene.Audio.PlayAt("jump", p.Center())
 		}
 	}
+	if p.Jumping && !input.JumpHeld() && p.Velocity.Y < 0 {
+		p.Velocity.Y *= p.Config.JumpCutMultiplier
+		p.Jumping = false
+	}
+	if input.DashPressed() && p.Dash() {
+		scene.Audio.PlayAt("dash", p.Center())
+	}
 
-	p.Position.Y += frameMovement.Y
-	rectsList = tilemap.TileMap.PhysicsRectsAroundPosition(p.Position)
-	entityRect = p.Rect()
-	for _, rect := range rectsList {
-		if entityRect.Colliderect(rect) {
-			if frameMovement.Y > 0 {
-				entityRect.SetBottom(rect.Top())
-				p.Collisions.Bottom = true
-			}
-			if frameMovement.Y < 0 {
-				entityRect.SetTop(rect.Bottom())
-				p.Collisions.Top = true
-			}
-			p.Position.Y = entityRect.Y
-		}
+	displacement := movement
+	if p.Ground.Friction < 1 && !p.IsDashing() {
+		// On slippery ground walking only eases the speed towards the input,
+		// which is kept in the velocity so the player slides on after letting go.
+		// The input itself still turns the player and picks the animation.
+		p.Velocity.X += (movement.X - p.Velocity.X) * p.Ground.Friction
+		displacement.X = 0
 	}
 
+	p.MoveAndCollide(scene.TileMap, displacement)
+
 	if p.Collisions.Bottom {
 		p.Jumps = 1
 		p.AirTime = 0
@@ -165,7 +211,7 @@ func (p *PlayerEntity) Update() error {
 
 	if (p.Collisions.Left || p.Collisions.Right) && p.AirTime > 4 {
 		p.WallSlide = true
-		p.Velocity.Y = math.Min(float64(p.Velocity.Y), 0.5)
+		p.Velocity.Y = math.Min(float64(p.Velocity.Y), p.Config.WallSlideSpeed)
 		if p.Collisions.Right {
 			p.Flipped = false
 		} else {
@@ -175,16 +221,16 @@ func (p *PlayerEntity) Update() error {
 		p.WallSlide = false
 	}
 
-	if !p.WallSlide {
-		if p.AirTime > 4 {
-			p.SetAction("jump")
-		} else if movement.X != 0 {
-			p.SetAction("run")
-		} else {
-			p.SetAction("idle")
-		}
-	} else {
-		p.SetAction("wall_slide")
+	if p.WallSlide {
+		// Counted down from the frame the player lets go, like AirTime counts up.
+		p.WallCoyote = p.Config.CoyoteFrames + 1
+		p.WallFlipped = p.Flipped
+	} else if p.WallCoyote > 0 {
+		p.WallCoyote--
+	}
+
+	if p.Velocity.Y >= 0 {
+		p.Jumping = false
 	}
 
 	rect := p.Rect()
@@ -193,31 +239,31 @@ func (p *PlayerEntity) Update() error {
 		Y: rect.CenterY(),
 	}
 
-	if math.Abs(p.Dashing) == 60 || math.Abs(p.Dashing) == 50 {
+	if math.Abs(p.Dashing) == float64(p.Config.DashFrames) || math.Abs(p.Dashing) == p.dashCooldown() {
 		for i := 0; i < 20; i++ {
-			angle := rand.Float64() * math.Pi * 2
-			speed := rand.Float64()*0.5 + 0.5
+			angle := scene.Rand.Float64() * math.Pi * 2
+			speed := scene.Rand.Float64()*0.5 + 0.5
 			velocity := types.Vector{
 				X: math.Cos(angle) * speed,
 				Y: math.Sin(angle) * speed,
 			}
-			particle.DashParticles.Particles =
-				append(particle.DashParticles.Particles, particle.CreateDashParticle(velocity, position))
+			scene.DashParticles.Particles =
+				append(scene.DashParticles.Particles, particle.CreateDashParticle(scene.Rand, velocity, position))
 		}
 	}
 
-	if math.Abs(p.Dashing) > 50 {
-		p.Velocity.X = math.Abs(p.Dashing) / p.Dashing * 8
-		if math.Abs(p.Dashing) == 51 {
+	if p.IsDashing() {
+		p.Velocity.X = math.Abs(p.Dashing) / p.Dashing * p.Config.DashSpeed
+		if math.Abs(p.Dashing) == p.dashCooldown()+1 {
 			p.Velocity.X *= 0.1
 		}
 
 		velocity := types.Vector{
-			X: math.Abs(p.Dashing) / p.Dashing * rand.Float64() * 3,
+			X: math.Abs(p.Dashing) / p.Dashing * scene.Rand.Float64() * 3,
 			Y: 0,
 		}
-		particle.DashParticles.Particles =
-			append(particle.DashParticles.Particles, particle.CreateDashParticle(velocity, position))
+		scene.DashParticles.Particles =
+			append(scene.DashParticles.Particles, particle.CreateDashParticle(scene.Rand, velocity, position))
 	}
 
 	if p.Dashing > 0 {
@@ -234,80 +280,57 @@ func (p *PlayerEntity) Update() error {
 		p.Flipped = true
 	}
 
+	friction := p.Config.Friction * p.Ground.Friction
 	if p.Velocity.X > 0 {
-		p.Velocity.X = math.Max(p.Velocity.X-0.1, 0)
+		p.Velocity.X = math.Max(p.Velocity.X-friction, 0)
 	} else if p.Velocity.X < 0 {
-		p.Velocity.X = math.Min(p.Velocity.X+0.1, 0)
+		p.Velocity.X = math.Min(p.Velocity.X+friction, 0)
 	}
 
-	p.Velocity.Y = math.Min(3, p.Velocity.Y+0.1)
+	p.ApplyGravity(p.Config.Gravity, p.Config.TerminalVelocity)
 
-	if p.Collisions.Bottom || p.Collisions.Top {
-		p.Velocity.Y = 0
-	}
+	p.Movement = movement
+	p.Machine.Update(scene)
+	p.Action = p.Machine.State()
 
 	return nil
 }
 
-var PlayerAnimations = map[string]*animation.Animation{
-	"idle": {
-		Images:        assets.Assets.Images["player_idle"].Image,
-		ImageDuration: 6,
-		Loop:          true,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
-	"run": {
-		Images:        assets.Assets.Images["player_run"].Image,
-		ImageDuration: 4,
-		Loop:          true,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
-	"jump": {
-		Images:        assets.Assets.Images["player_jump"].Image,
-		ImageDuration: 5,
-		Loop:          false,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -2.5,
-		},
-	},
-	"slide": {
-		Images:        assets.Assets.Images["player_slide"].Image,
-		ImageDuration: 5,
-		Loop:          false,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
-	"wall_slide": {
-		Images:        assets.Assets.Images["player_wall_slide"].Image,
-		ImageDuration: 5,
-		Loop:          false,
-		Done:          false,
-		Offset: types.Vector{
-			X: -3,
-			Y: -3,
-		},
-	},
+func newPlayerStateMachine(p *PlayerEntity) *animation.StateMachine[*Scene] {
+	machine := animation.NewStateMachine[*Scene](p.Animator)
+
+	machine.AddTransition("", "wall_slide", 4, func(scene *Scene) bool { return p.WallSlide })
+	machine.AddTransition("", "jump", 3, func(scene *Scene) bool { return p.AirTime > 4 })
+	machine.AddTransition("", "slide", 2, func(scene *Scene) bool { return math.Abs(p.Velocity.X) > 1 })
+	machine.AddTransition("", "run", 1, func(scene *Scene) bool { return p.Movement.X != 0 })
+	machine.AddTransition("", "idle", 0, func(scene *Scene) bool { return true })
+
+	machine.OnFrame("run", 1, func(scene *Scene) {
+		rect := p.Rect()
+		position := types.Vector{X: rect.CenterX(), Y: rect.Bottom()}
+		velocity := types.Vector{X: -p.Movement.X * scene.Rand.Float64() * 0.5, Y: -scene.Rand.Float64() * 0.3}
+		scene.DashParticles.Particles =
+			append(scene.DashParticles.Particles, particle.CreateDashParticle(scene.Rand, velocity, position))
+	})
+
+	return machine
+}
+
+func PlayerClips() map[string]*animation.Clip {
+	return assets.Assets.SheetClips("player")
 }
 
-var Player = &PlayerEntity{
-	EntityType: "player",
-	Position:   types.Vector{X: 0, Y: 0},
-	Velocity:   types.Vector{X: 0, Y: 0},
-	Collisions: types.Collisions{},
-	Action:     "idle",
-	Animations: PlayerAnimations,
-	Jumps:      1,
+func CreatePlayer(position types.Vector, config *PlayerConfig) *PlayerEntity {
+	player := &PlayerEntity{
+		Body:       bodyForAsset(position, "player"),
+		EntityType: "player",
+		Action:     "idle",
+		Animator:   animation.NewAnimator(PlayerClips(), "idle"),
+		Jumps:      1,
+		Config:     config,
+		Health:     config.MaxHealth,
+	}
+	player.Machine = newPlayerStateMachine(player)
+
+	return player
 }
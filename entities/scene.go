@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"math/rand"
+
+	"github.com/yuricorredor/platformer/audio"
+	"github.com/yuricorredor/platformer/particle"
+	"github.com/yuricorredor/platformer/tilemap"
+)
+
+// Scene is everything an entity can see and affect while updating. It is
+// owned by whoever drives the simulation, so entities never reach for globals.
+type Scene struct {
+	Rand          *rand.Rand
+	TileMap       *tilemap.TileMapType
+	Player        *PlayerEntity
+	DashParticles *particle.DashParticlesType
+	Projectiles   *particle.ProjectilesType
+	Sparks        *particle.SparksType
+	Audio         audio.Player
+}
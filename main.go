@@ -1,28 +1,78 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
+	"math/rand"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/yuricorredor/platformer/assets"
-	"github.com/yuricorredor/platformer/clouds"
+	"github.com/yuricorredor/platformer/audio"
 	"github.com/yuricorredor/platformer/entities"
-	"github.com/yuricorredor/platformer/particle"
+	"github.com/yuricorredor/platformer/replay"
 	"github.com/yuricorredor/platformer/tilemap"
 	"github.com/yuricorredor/platformer/types"
+	"github.com/yuricorredor/platformer/world"
 )
 
 var (
-	gameClouds = &clouds.CloudsType{
-		CloudImages: assets.Assets.Images["clouds"].Image,
-		Count:       16,
-	}
-	leafs   = &particle.Leafs{}
-	enemies = []*entities.EnemyEntity{}
+	replayPath = flag.String("replay", "", "play back a recorded replay file")
+	recordPath = flag.String("record", "", "record the session to a replay file")
+	dataPath   = flag.String("data", "", "directory whose files override the embedded assets, reloaded when they change")
+)
+
+// ReloadPollFrames is how often the override directory is checked for edits,
+// RecordSaveFrames how often the recording is written out, so a crash only
+// loses the last few seconds.
+const (
+	ReloadPollFrames = 30
+	RecordSaveFrames = 600
 )
 
+// SoundsPath holds the sfx, as .wav files or sfxr presets.
+const SoundsPath = "sfx"
+
+// VolumeStep is how much the volume keys change a mixer channel, and
+// MixerDisplayFrames how long the volumes stay on screen afterwards.
+const (
+	VolumeStep         = 0.1
+	MixerDisplayFrames = 120
+)
+
+// volumeKeys lower and raise each mixer channel.
+var volumeKeys = []struct {
+	down, up ebiten.Key
+	volume   func(*audio.Mixer) *float64
+}{
+	{ebiten.KeyMinus, ebiten.KeyEqual, func(m *audio.Mixer) *float64 { return &m.Master }},
+	{ebiten.KeyBracketLeft, ebiten.KeyBracketRight, func(m *audio.Mixer) *float64 { return &m.Music }},
+	{ebiten.KeySemicolon, ebiten.KeyQuote, func(m *audio.Mixer) *float64 { return &m.Sfx }},
+	{ebiten.KeyComma, ebiten.KeyPeriod, func(m *audio.Mixer) *float64 { return &m.Ambience }},
+}
+
 type Game struct {
+	world        *world.World
+	mapId        int
+	input        entities.InputSource
+	playerConfig *entities.PlayerConfig
+	sounds       audio.Player
+	music        *audio.Music
+	mixer        *audio.Mixer
+	settingsPath string
+	mixerShown   int
+	recording    *replay.Replay
+	playback     *replay.Playback
+	watcher      *assets.Watcher
+	frame        int
 	scollX       int
 	scrollY      int
 	screenWidth  int
@@ -30,36 +80,91 @@ type Game struct {
 }
 
 func (g *Game) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) && g.canReload() {
+		if err := g.playerConfig.Reload(assets.FS, entities.PlayerConfigPath); err != nil {
+			log.Println(err)
+		}
+	}
+
+	g.frame++
+
+	g.updateMixer()
+	g.music.Update()
+	if g.watcher != nil && g.canReload() && g.frame%ReloadPollFrames == 0 {
+		g.hotReload()
+	}
+	if g.recording != nil && *recordPath != "" && g.frame%RecordSaveFrames == 0 {
+		if err := g.recording.Save(*recordPath); err != nil {
+			log.Println(err)
+		}
+	}
+
 	g.updateScrollPosition()
-	gameClouds.Update()
-	entities.Player.Update()
+	g.sounds.SetListener(types.Vector{
+		X: float64(g.scollX + g.screenWidth/2),
+		Y: float64(g.scrollY + g.screenHeight/2),
+	})
+
+	if g.playback != nil {
+		input, ok := g.playback.Next()
+		if !ok {
+			return nil
+		}
+		g.world.Step(input)
+	} else {
+		g.world.Step(g.recording.Record(g.input))
+	}
 
-	for _, enemy := range enemies {
-		enemy.Update()
+	if g.world.Finished() {
+		return g.nextMap()
 	}
 
-	particle.DashParticles.Update()
-	particle.Projectiles.Update(entities.Player.Dashing, entities.Player.Rect())
-	particle.SparksParticles.Update()
-	leafs.Update()
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	screen.DrawImage(assets.Assets.Images["background"].Image[0], nil)
+	shakeX, shakeY := 0, 0
+	if shake := g.world.ScreenShake; shake > 0 {
+		shakeX = rand.Intn(shake) - shake/2
+		shakeY = rand.Intn(shake) - shake/2
+	}
+
+	g.world.Draw(screen, g.scollX+shakeX, g.scrollY+shakeY)
 
-	gameClouds.Draw(screen, g.scollX, g.scrollY)
-	tilemap.TileMap.Draw(screen, g.scollX, g.scrollY, "game")
-	entities.Player.Draw(screen, g.scollX, g.scrollY)
+	if g.mixerShown > 0 {
+		ebitenutil.DebugPrint(screen, fmt.Sprintf("master %.0f%%  music %.0f%%  sfx %.0f%%  ambience %.0f%%",
+			g.mixer.Master*100, g.mixer.Music*100, g.mixer.Sfx*100, g.mixer.Ambience*100))
+	}
+}
 
-	for _, enemy := range enemies {
-		enemy.Draw(screen, g.scollX, g.scrollY)
+// updateMixer applies the volume keys. The mixer is saved on every change,
+// so the settings survive the game being killed.
+func (g *Game) updateMixer() {
+	if g.mixerShown > 0 {
+		g.mixerShown--
 	}
 
-	particle.DashParticles.Draw(screen, g.scollX, g.scrollY)
-	particle.Projectiles.Draw(screen, g.scollX, g.scrollY)
-	particle.SparksParticles.Draw(screen, g.scollX, g.scrollY)
-	leafs.Draw(screen, g.scollX, g.scrollY)
+	changed := false
+	for _, keys := range volumeKeys {
+		if inpututil.IsKeyJustPressed(keys.down) {
+			audio.Adjust(keys.volume(g.mixer), -VolumeStep)
+			changed = true
+		}
+		if inpututil.IsKeyJustPressed(keys.up) {
+			audio.Adjust(keys.volume(g.mixer), VolumeStep)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	g.mixerShown = MixerDisplayFrames
+	if g.settingsPath != "" {
+		if err := g.mixer.Save(g.settingsPath); err != nil {
+			log.Println(err)
+		}
+	}
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -69,44 +174,204 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 }
 
 func (g *Game) updateScrollPosition() {
-	playerRect := entities.Player.Rect()
+	playerRect := g.world.Player.Rect()
 	g.scollX += (int(playerRect.CenterX()) - g.screenWidth/2 - g.scollX) / 15
 	g.scrollY += (int(playerRect.CenterY()) - g.screenHeight/2 - g.scrollY) / 15
 }
 
-func (g *Game) loadMap(mapId int) {
-	tilemap.TileMap.Load("assets/data/maps/" + strconv.Itoa(mapId) + ".json")
-
-	gameClouds = &clouds.CloudsType{
-		CloudImages: assets.Assets.Images["clouds"].Image,
-		Count:       16,
-	}
-	enemies = []*entities.EnemyEntity{}
-
-	gameClouds.GenerateRandomClouds()
-	leafs = particle.CreateLeafs()
-
-	for _, spawner := range tilemap.TileMap.Extract([]types.Pair{
-		{
-			AssetType:    "spawners",
-			AssetVariant: 0,
-		},
-		{
-			AssetType:    "spawners",
-			AssetVariant: 1,
-		},
-	}, true) {
-		if spawner.Variant == 0 {
-			entities.Player.Position = spawner.Position
+// canReload reports whether settings and assets may change. They are frozen
+// while playing a replay back, as they were when it was recorded, and while
+// recording, since the replay only keeps the settings it started with.
+func (g *Game) canReload() bool {
+	return g.playback == nil && *recordPath == ""
+}
+
+// hotReload reloads whatever changed in the override directory. Errors are
+// only logged, the game keeps going with what it had.
+func (g *Game) hotReload() {
+	assetsChanged, configChanged, tileTypesChanged, mapChanged, soundsChanged := false, false, false, false, false
+	for _, file := range g.watcher.Changed() {
+		switch {
+		case file == assets.ManifestPath || strings.HasPrefix(file, assets.BasePath):
+			assetsChanged = true
+		case file == entities.PlayerConfigPath:
+			configChanged = true
+		case file == tilemap.TileTypesPath:
+			tileTypesChanged = true
+		case file == mapPath(g.mapId):
+			mapChanged = true
+		case strings.HasPrefix(file, SoundsPath+"/"):
+			soundsChanged = true
+		}
+	}
+
+	if assetsChanged {
+		previous := assets.Assets
+		if err := assets.Load(assets.FS); err != nil {
+			log.Println(err)
+		} else if err := world.CheckAssets(); err != nil {
+			assets.Assets = previous
+			log.Println(err)
+		} else if err := g.world.TileMap.Validate(); err != nil {
+			assets.Assets = previous
+			log.Println(err)
+		} else {
+			g.world.RefreshAssets()
+		}
+	}
+
+	if configChanged {
+		if err := g.playerConfig.Reload(assets.FS, entities.PlayerConfigPath); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if tileTypesChanged {
+		if tileTypes, err := tilemap.LoadTileTypes(assets.FS, tilemap.TileTypesPath); err != nil {
+			log.Println(err)
 		} else {
-			enemies = append(enemies, entities.CreateEnemy(spawner.Position))
+			tilemap.TileTypes = tileTypes
+		}
+	}
+
+	if sounds, ok := g.sounds.(*audio.Sounds); ok && soundsChanged {
+		if err := sounds.Reload(assets.FS, SoundsPath); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if mapChanged {
+		if err := g.world.ReloadMap(assets.FS, mapPath(g.mapId)); err != nil {
+			log.Println(err)
 		}
+		g.playMapMusic()
+	}
+}
+
+func mapPath(mapId int) string {
+	return "maps/" + strconv.Itoa(mapId) + ".json"
+}
+
+func (g *Game) loadMap(mapId int, seed int64) error {
+	w, err := world.New(assets.FS, mapPath(mapId), seed, g.playerConfig)
+	if err != nil {
+		return err
 	}
+
+	w.Audio = g.sounds
+	g.world = w
+	g.mapId = mapId
+
+	g.playMapMusic()
+	return nil
+}
+
+// playMapMusic crossfades to the tracks named by the current map.
+func (g *Game) playMapMusic() {
+	playlist := g.world.TileMap.Playlist
+	if len(playlist) == 0 && g.world.TileMap.Music != "" {
+		playlist = []string{g.world.TileMap.Music}
+	}
+	if err := g.music.PlayPlaylist(playlist); err != nil {
+		log.Println(err)
+	}
+	if err := g.music.PlayAmbience(g.world.TileMap.Ambience); err != nil {
+		log.Println(err)
+	}
+}
+
+// nextMap moves on to the next numbered map, going back to the first one once
+// there are no more. The seed comes from the current world so replays follow
+// the same path.
+func (g *Game) nextMap() error {
+	seed := g.world.Rand.Int63()
+	err := g.loadMap(g.mapId+1, seed)
+	if errors.Is(err, fs.ErrNotExist) {
+		return g.loadMap(0, seed)
+	}
+
+	return err
 }
 
 func main() {
-	game := &Game{}
-	game.loadMap(0)
+	flag.Parse()
+
+	if *dataPath != "" {
+		assets.UseOverride(*dataPath)
+	}
+	if err := assets.Load(assets.FS); err != nil {
+		log.Fatal(err)
+	}
+	if err := world.CheckAssets(); err != nil {
+		log.Fatal(err)
+	}
+
+	playerConfig, err := entities.LoadPlayerConfig(assets.FS, entities.PlayerConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tileTypes, err := tilemap.LoadTileTypes(assets.FS, tilemap.TileTypesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.TileTypes = tileTypes
+
+	settingsPath, err := audio.SettingsPath()
+	if err != nil {
+		log.Println(err)
+	}
+	mixer := audio.DefaultMixer()
+	if settingsPath != "" {
+		if mixer, err = audio.LoadMixer(settingsPath); err != nil {
+			log.Println(err)
+		}
+	}
+
+	music := audio.NewMusic(assets.FS, "music", mixer)
+	var sounds audio.Player = audio.Null{}
+	if loaded, err := audio.Load(assets.FS, SoundsPath, mixer); err != nil {
+		log.Println(err)
+	} else {
+		loaded.Music = music
+		sounds = loaded
+	}
+
+	game := &Game{
+		input:        entities.KeyboardInput{},
+		playerConfig: playerConfig,
+		sounds:       sounds,
+		music:        music,
+		mixer:        mixer,
+		settingsPath: settingsPath,
+	}
+	if *dataPath != "" && *recordPath != "" {
+		log.Println("hot reload is off while recording")
+	}
+	if *dataPath != "" && *replayPath == "" && *recordPath == "" {
+		game.watcher = assets.NewWatcher(assets.FS, assets.ManifestPath, path.Clean(assets.BasePath), "maps", SoundsPath, entities.PlayerConfigPath, tilemap.TileTypesPath)
+	}
+
+	if *replayPath != "" {
+		recorded, err := replay.Load(*replayPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if recorded.ConfigHash != 0 && recorded.ConfigHash != replay.Hash(playerConfig, tilemap.TileTypes) {
+			log.Println("replay was recorded with another player config or tile types, it may play out differently")
+		}
+
+		game.playback = replay.NewPlayback(recorded)
+		if err := game.loadMap(recorded.MapId, recorded.Seed); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		game.recording = replay.New(time.Now().UnixNano(), 0, replay.Hash(playerConfig, tilemap.TileTypes))
+		if err := game.loadMap(game.recording.MapId, game.recording.Seed); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	ebiten.SetWindowSize(640, 480)
 	ebiten.SetWindowTitle("Platformer")
@@ -114,4 +379,10 @@ func main() {
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
+
+	if game.recording != nil && *recordPath != "" {
+		if err := game.recording.Save(*recordPath); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
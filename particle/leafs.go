@@ -5,7 +5,6 @@ import (
 	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/rects"
 	"github.com/yuricorredor/platformer/tilemap"
@@ -17,31 +16,37 @@ type Leafs struct {
 	Spawners  []rects.Rect
 }
 
-func (l *Leafs) Update() {
+func (l *Leafs) Update(rng *rand.Rand) {
 	for _, rect := range l.Spawners {
-		if rand.Float64()*40000 < rect.Width*rect.Height {
+		if rng.Float64()*40000 < rect.Width*rect.Height {
 			position := types.Vector{
-				X: float64(rect.X) + rand.Float64()*float64(rect.Width),
-				Y: float64(rect.Y) + rand.Float64()*float64(rect.Height),
+				X: float64(rect.X) + rng.Float64()*float64(rect.Width),
+				Y: float64(rect.Y) + rng.Float64()*float64(rect.Height),
 			}
 
-			l.Particles = append(l.Particles, newLeaf(position))
+			l.Particles = append(l.Particles, newLeaf(rng, position))
 		}
 	}
-}
 
-func (l *Leafs) Draw(screen *ebiten.Image, scollX, scollY int) {
+	remainingParticles := []*Particle{}
 	for _, particle := range l.Particles {
 		kill := particle.Update()
-		particle.Draw(screen, scollX, scollY)
 		particle.Position.X += math.Sin(float64(particle.Animation.Frame)*0.035) * 0.3
-		if kill {
-			l.Particles = l.Particles[1:]
+		if !kill {
+			remainingParticles = append(remainingParticles, particle)
 		}
 	}
+
+	l.Particles = remainingParticles
 }
 
-func CreateLeafs() *Leafs {
+func (l *Leafs) Draw(screen *ebiten.Image, scollX, scollY int) {
+	for _, particle := range l.Particles {
+		particle.Draw(screen, scollX, scollY)
+	}
+}
+
+func CreateLeafs(tileMap *tilemap.TileMapType) *Leafs {
 	leafs := &Leafs{
 		Particles: []*Particle{},
 		Spawners:  []rects.Rect{},
@@ -54,12 +59,12 @@ func CreateLeafs() *Leafs {
 		},
 	}
 
-	trees := tilemap.TileMap.Extract(tree_asset_pair, true)
+	trees := tileMap.Extract(tree_asset_pair, true)
 
 	for _, tree := range trees {
 		treeRect := rects.Rect{
-			X:      tree.Position.X*float64(tilemap.TileMap.TileSize) + 4,
-			Y:      tree.Position.Y*float64(tilemap.TileMap.TileSize) + 4,
+			X:      tree.Position.X*float64(tileMap.TileSize) + 4,
+			Y:      tree.Position.Y*float64(tileMap.TileSize) + 4,
 			Width:  24,
 			Height: 12,
 		}
@@ -70,7 +75,7 @@ func CreateLeafs() *Leafs {
 	return leafs
 }
 
-func newLeaf(position types.Vector) *Particle {
+func newLeaf(rng *rand.Rand, position types.Vector) *Particle {
 	return &Particle{
 		Type:     "leaf",
 		Position: position,
@@ -78,12 +83,7 @@ func newLeaf(position types.Vector) *Particle {
 			X: -0.1,
 			Y: 0.3,
 		},
-		Frame: rand.Intn(len(assets.Assets.Images["particle_leaf"].Image) - 1),
-		Animation: animation.Animation{
-			Images:        assets.Assets.Images["particle_leaf"].Image,
-			ImageDuration: 20,
-			Loop:          false,
-			Done:          false,
-		},
+		Frame:     rng.Intn(len(assets.Assets.Images["particle_leaf"].Image) - 1),
+		Animation: assets.Assets.Animation("particle_leaf"),
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/types"
 )
@@ -29,19 +28,12 @@ func (d *DashParticlesType) Draw(screen *ebiten.Image, scollX, scollY int) {
 	}
 }
 
-func CreateDashParticle(velocity types.Vector, position types.Vector) *Particle {
+func CreateDashParticle(rng *rand.Rand, velocity types.Vector, position types.Vector) *Particle {
 	return &Particle{
-		Type:     "particle",
-		Position: position,
-		Velocity: velocity,
-		Frame:    rand.Intn(7),
-		Animation: animation.Animation{
-			Images:        assets.Assets.Images["particle"].Image,
-			ImageDuration: 6,
-			Loop:          false,
-			Done:          false,
-		},
+		Type:      "particle",
+		Position:  position,
+		Velocity:  velocity,
+		Frame:     rng.Intn(7),
+		Animation: assets.Assets.Animation("particle"),
 	}
 }
-
-var DashParticles = &DashParticlesType{}
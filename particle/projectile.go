@@ -1,10 +1,7 @@
 package particle
 
 import (
-	"math"
-
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/rects"
 	"github.com/yuricorredor/platformer/tilemap"
@@ -15,8 +12,10 @@ type ProjectilesType struct {
 	Particles []*Particle
 }
 
-func (projectile *ProjectilesType) Update(playerDashing float64, playerRect rects.Rect) {
+// Update moves the projectiles and returns how many of them hit the player.
+func (projectile *ProjectilesType) Update(tileMap *tilemap.TileMapType, playerDashing bool, playerRect rects.Rect) int {
 	var remainingParticles []*Particle
+	hits := 0
 
 	for _, particle := range projectile.Particles {
 
@@ -25,9 +24,14 @@ func (projectile *ProjectilesType) Update(playerDashing float64, playerRect rect
 		particle.Position.X += particle.Velocity.X
 		particle.Position.Y += particle.Velocity.Y
 
+		hitPlayer := !playerDashing && playerRect.Colliderect(particle.Rect())
+		if hitPlayer {
+			hits++
+		}
+
 		shouldRemoveParticle := particle.Frame > 360 ||
-			tilemap.TileMap.CheckForSolid(particle.Position) ||
-			(math.Abs(playerDashing) < 50 && playerRect.Colliderect(particle.Rect()))
+			tileMap.CheckForSolid(particle.Position) ||
+			hitPlayer
 
 		if !shouldRemoveParticle {
 			remainingParticles = append(remainingParticles, particle)
@@ -35,6 +39,7 @@ func (projectile *ProjectilesType) Update(playerDashing float64, playerRect rect
 	}
 
 	projectile.Particles = remainingParticles
+	return hits
 }
 
 func (projectile *ProjectilesType) Draw(screen *ebiten.Image, scollX, scollY int) {
@@ -45,17 +50,10 @@ func (projectile *ProjectilesType) Draw(screen *ebiten.Image, scollX, scollY int
 
 func NewProjectile(position, velocity types.Vector) *Particle {
 	return &Particle{
-		Type:     "projectile",
-		Position: position,
-		Velocity: velocity,
-		Frame:    0,
-		Animation: animation.Animation{
-			Images:        assets.Assets.Images["projectile"].Image,
-			ImageDuration: 600,
-			Loop:          false,
-			Done:          false,
-		},
+		Type:      "projectile",
+		Position:  position,
+		Velocity:  velocity,
+		Frame:     0,
+		Animation: assets.Assets.Animation("projectile"),
 	}
 }
-
-var Projectiles = &ProjectilesType{}
@@ -6,7 +6,6 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/yuricorredor/platformer/animation"
 	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/types"
 )
@@ -94,18 +93,11 @@ func (spark *SparksType) Draw(screen *ebiten.Image, scollX, scollY int) {
 
 func NewSpark(angle float64, position, velocity types.Vector) *Particle {
 	return &Particle{
-		Type:     "spark",
-		Angle:    angle,
-		Position: position,
-		Velocity: velocity,
-		Frame:    0,
-		Animation: animation.Animation{
-			Images:        assets.Assets.Images["projectile"].Image,
-			ImageDuration: 600,
-			Loop:          false,
-			Done:          false,
-		},
+		Type:      "spark",
+		Angle:     angle,
+		Position:  position,
+		Velocity:  velocity,
+		Frame:     0,
+		Animation: assets.Assets.Animation("projectile"),
 	}
 }
-
-var SparksParticles = &SparksType{}
@@ -0,0 +1,243 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+
+	"github.com/yuricorredor/platformer/entities"
+)
+
+// Replay files start with a magic header and a version byte, followed by the
+// seed, the map id, the config hash and the frames run-length encoded as
+// (actions, count) pairs. Version 2 files have no config hash.
+const (
+	magic   = "PLRP"
+	version = 3
+)
+
+// MaxFrames caps the length of a replay, a bit over three days at 60 frames
+// per second, so a corrupt file can't make decode run away.
+const MaxFrames = 1 << 24
+
+const (
+	actionLeft byte = 1 << iota
+	actionRight
+	actionJump
+	actionDash
+	actionHoldJump
+)
+
+var ErrInvalidReplay = errors.New("replay: invalid replay file")
+
+// ConfigHash identifies the settings the replay was recorded with, see Hash.
+// It is zero for replays recorded before it existed.
+type Replay struct {
+	Seed       int64
+	MapId      int
+	ConfigHash uint64
+	Frames     []entities.Input
+}
+
+func New(seed int64, mapId int, configHash uint64) *Replay {
+	return &Replay{
+		Seed:       seed,
+		MapId:      mapId,
+		ConfigHash: configHash,
+		Frames:     []entities.Input{},
+	}
+}
+
+// Hash digests the settings a replay depends on besides its inputs, such as
+// the player config and the tile types, so playback can tell when they
+// changed since the recording.
+func Hash(settings ...any) uint64 {
+	h := fnv.New64a()
+	for _, setting := range settings {
+		fmt.Fprintf(h, "%+v\n", setting)
+	}
+
+	return h.Sum64()
+}
+
+// Record samples the source once and stores the result as the next frame. The
+// returned input should be fed to the world instead of the source itself, so
+// the recording is exactly what the simulation saw.
+func (r *Replay) Record(source entities.InputSource) entities.Input {
+	input := entities.Input{
+		Left:     source.MoveLeft(),
+		Right:    source.MoveRight(),
+		Jump:     source.JumpPressed(),
+		Dash:     source.DashPressed(),
+		HoldJump: source.JumpHeld(),
+	}
+
+	// Frames past MaxFrames could not be loaded back, they are dropped.
+	if len(r.Frames) < MaxFrames {
+		r.Frames = append(r.Frames, input)
+	}
+	return input
+}
+
+func (r *Replay) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err := r.encode(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func Load(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return decode(bufio.NewReader(f))
+}
+
+func (r *Replay) encode(w io.Writer) error {
+	buffer := []byte(magic)
+	buffer = append(buffer, version)
+	buffer = binary.AppendVarint(buffer, r.Seed)
+	buffer = binary.AppendUvarint(buffer, uint64(r.MapId))
+	buffer = binary.LittleEndian.AppendUint64(buffer, r.ConfigHash)
+
+	for i := 0; i < len(r.Frames); {
+		actions := encodeInput(r.Frames[i])
+		count := 1
+		for i+count < len(r.Frames) && encodeInput(r.Frames[i+count]) == actions {
+			count++
+		}
+
+		buffer = append(buffer, actions)
+		buffer = binary.AppendUvarint(buffer, uint64(count))
+		i += count
+	}
+
+	_, err := w.Write(buffer)
+	return err
+}
+
+func decode(r *bufio.Reader) (*Replay, error) {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrInvalidReplay
+	}
+	fileVersion := header[len(magic)]
+	if string(header[:len(magic)]) != magic || fileVersion < 2 || fileVersion > version {
+		return nil, ErrInvalidReplay
+	}
+
+	seed, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, ErrInvalidReplay
+	}
+	mapId, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrInvalidReplay
+	}
+
+	var configHash uint64
+	if fileVersion >= 3 {
+		hash := make([]byte, 8)
+		if _, err := io.ReadFull(r, hash); err != nil {
+			return nil, ErrInvalidReplay
+		}
+		configHash = binary.LittleEndian.Uint64(hash)
+	}
+
+	replay := New(seed, int(mapId), configHash)
+	for {
+		actions, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := binary.ReadUvarint(r)
+		if err != nil || count > uint64(MaxFrames-len(replay.Frames)) {
+			return nil, ErrInvalidReplay
+		}
+
+		input := decodeInput(actions)
+		for i := uint64(0); i < count; i++ {
+			replay.Frames = append(replay.Frames, input)
+		}
+	}
+
+	return replay, nil
+}
+
+func encodeInput(input entities.Input) byte {
+	var actions byte
+	if input.Left {
+		actions |= actionLeft
+	}
+	if input.Right {
+		actions |= actionRight
+	}
+	if input.Jump {
+		actions |= actionJump
+	}
+	if input.Dash {
+		actions |= actionDash
+	}
+	if input.HoldJump {
+		actions |= actionHoldJump
+	}
+
+	return actions
+}
+
+func decodeInput(actions byte) entities.Input {
+	return entities.Input{
+		Left:     actions&actionLeft != 0,
+		Right:    actions&actionRight != 0,
+		Jump:     actions&actionJump != 0,
+		Dash:     actions&actionDash != 0,
+		HoldJump: actions&actionHoldJump != 0,
+	}
+}
+
+// Playback hands out the recorded frames in order.
+type Playback struct {
+	replay *Replay
+	frame  int
+}
+
+func NewPlayback(replay *Replay) *Playback {
+	return &Playback{replay: replay}
+}
+
+func (p *Playback) Next() (entities.Input, bool) {
+	if p.Done() {
+		return entities.Input{}, false
+	}
+
+	input := p.replay.Frames[p.frame]
+	p.frame++
+	return input, true
+}
+
+func (p *Playback) Done() bool {
+	return p.frame >= len(p.replay.Frames)
+}
@@ -0,0 +1,121 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/entities"
+	"github.com/yuricorredor/platformer/world"
+)
+
+func TestMain(m *testing.M) {
+	if err := assets.Load(assets.FS); err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// scriptedInputs walks right, jumps, dashes and walks back, with runs of
+// repeated frames like real play.
+func scriptedInputs() []entities.Input {
+	frames := []entities.Input{}
+	add := func(input entities.Input, count int) {
+		for i := 0; i < count; i++ {
+			frames = append(frames, input)
+		}
+	}
+
+	add(entities.Input{}, 30)
+	add(entities.Input{Right: true}, 60)
+	add(entities.Input{Right: true, Jump: true, HoldJump: true}, 1)
+	add(entities.Input{Right: true, HoldJump: true}, 20)
+	add(entities.Input{Dash: true}, 1)
+	add(entities.Input{Left: true}, 90)
+
+	return frames
+}
+
+func TestEncodeDecode(t *testing.T) {
+	recorded := New(-42, 3, Hash(entities.DefaultPlayerConfig()))
+	for _, input := range scriptedInputs() {
+		recorded.Record(input)
+	}
+
+	var buffer bytes.Buffer
+	if err := recorded.encode(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decode(bufio.NewReader(&buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, recorded) {
+		t.Errorf("decoded replay differs:\ngot  %+v\nwant %+v", decoded, recorded)
+	}
+}
+
+func TestDecodeRejectsHugeRuns(t *testing.T) {
+	buffer := []byte(magic)
+	buffer = append(buffer, version)
+	buffer = binary.AppendVarint(buffer, 1)
+	buffer = binary.AppendUvarint(buffer, 0)
+	buffer = binary.LittleEndian.AppendUint64(buffer, 0)
+	buffer = append(buffer, actionLeft)
+	buffer = binary.AppendUvarint(buffer, MaxFrames+1)
+
+	if _, err := decode(bufio.NewReader(bytes.NewReader(buffer))); !errors.Is(err, ErrInvalidReplay) {
+		t.Errorf("decode = %v, want %v", err, ErrInvalidReplay)
+	}
+}
+
+func TestHashChangesWithConfig(t *testing.T) {
+	config := entities.DefaultPlayerConfig()
+	before := Hash(config)
+	config.JumpVelocity++
+
+	if Hash(config) == before {
+		t.Error("hash did not change with the player config")
+	}
+}
+
+func TestPlaybackIsDeterministic(t *testing.T) {
+	recorded := New(7, 0, 0)
+	for _, input := range scriptedInputs() {
+		recorded.Record(input)
+	}
+
+	play := func() []world.Snapshot {
+		w, err := world.New(assets.FS, "maps/0.json", recorded.Seed, entities.DefaultPlayerConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		snapshots := []world.Snapshot{}
+		playback := NewPlayback(recorded)
+		for input, ok := playback.Next(); ok; input, ok = playback.Next() {
+			w.Step(input)
+			snapshots = append(snapshots, w.Snapshot())
+		}
+
+		return snapshots
+	}
+
+	first, second := play(), play()
+	if len(first) != len(recorded.Frames) {
+		t.Fatalf("played %d frames, want %d", len(first), len(recorded.Frames))
+	}
+	for i := range first {
+		if !reflect.DeepEqual(first[i], second[i]) {
+			t.Fatalf("frame %d differs:\n%+v\n%+v", i, first[i], second[i])
+		}
+	}
+}
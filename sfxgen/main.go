@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuricorredor/platformer/sfxr"
+)
+
+var (
+	OUT_PATH = flag.String("out", ".", "directory the WAV files are written to")
+	FORCE    = flag.Bool("force", false, "overwrite WAV files that already exist")
+)
+
+// volumesFile sits next to the presets the game plays in assets/data/sfx but
+// isn't one, see audio.VolumesFile.
+const volumesFile = "volumes.json"
+
+// Renders every preset given on the command line, such as
+// assets/data/sfxr/*.json, into a WAV file of the same name.
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: sfxgen [-out dir] [-force] preset.json...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, path := range flag.Args() {
+		if filepath.Base(path) == volumesFile {
+			continue
+		}
+
+		params, err := sfxr.LoadParams(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		out := filepath.Join(*OUT_PATH, name+".wav")
+		if _, err := os.Stat(out); !*FORCE && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("%s already exists, use -force to overwrite it", out)
+		}
+		if err := os.WriteFile(out, sfxr.Render(params), 0644); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("%s -> %s", path, out)
+	}
+}
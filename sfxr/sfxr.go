@@ -0,0 +1,172 @@
+package sfxr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+)
+
+const SampleRate = 44100
+
+// MaxDuration caps Attack + Sustain + Decay, in seconds, so a typo in a preset
+// can't allocate without limit.
+const MaxDuration = 10
+
+type Wave string
+
+const (
+	Square   Wave = "square"
+	Sawtooth Wave = "sawtooth"
+	Sine     Wave = "sine"
+	Noise    Wave = "noise"
+)
+
+// Params describe a sound the way sfxr does. Times are in seconds and
+// frequencies in Hz. Slide bends the pitch by octaves per second, DeltaSlide
+// changes Slide over time, and the sound stops early once the pitch falls
+// under MinFrequency. Noise mixes white noise over any wave.
+type Params struct {
+	Wave         Wave
+	Volume       float64
+	Attack       float64
+	Sustain      float64
+	Punch        float64
+	Decay        float64
+	Frequency    float64
+	MinFrequency float64
+	Slide        float64
+	DeltaSlide   float64
+	VibratoDepth float64
+	VibratoSpeed float64
+	DutyCycle    float64
+	Noise        float64
+	Seed         int64
+}
+
+func DefaultParams() *Params {
+	return &Params{
+		Wave:      Square,
+		Volume:    0.5,
+		Sustain:   0.1,
+		Decay:     0.2,
+		Frequency: 440,
+		DutyCycle: 0.5,
+	}
+}
+
+// LoadParams reads a JSON preset. Fields left out keep their default value.
+func LoadParams(fsys fs.FS, path string) (*Params, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := DefaultParams()
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, err
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// Validate rejects params that can't be rendered: negative times, a sound
+// longer than MaxDuration or a frequency that isn't positive.
+func (p *Params) Validate() error {
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{{"Attack", p.Attack}, {"Sustain", p.Sustain}, {"Decay", p.Decay}} {
+		if !(field.value >= 0) {
+			return fmt.Errorf("%s is %v, it can't be negative", field.name, field.value)
+		}
+	}
+	if duration := p.Attack + p.Sustain + p.Decay; duration > MaxDuration {
+		return fmt.Errorf("the sound lasts %vs, more than %vs", duration, MaxDuration)
+	}
+	if !(p.Frequency > 0) {
+		return fmt.Errorf("frequency is %v, it must be positive", p.Frequency)
+	}
+
+	return nil
+}
+
+// Synthesize renders the sound as mono samples at SampleRate. The same
+// params always give the same samples.
+func Synthesize(p *Params) []int16 {
+	rng := rand.New(rand.NewSource(p.Seed))
+	length := int((p.Attack + p.Sustain + p.Decay) * SampleRate)
+	samples := make([]int16, 0, length)
+
+	frequency := p.Frequency
+	slide := p.Slide
+	phase := 0.0
+	noise := rng.Float64()*2 - 1
+
+	for i := 0; i < length; i++ {
+		t := float64(i) / SampleRate
+
+		slide += p.DeltaSlide / SampleRate
+		frequency *= math.Pow(2, slide/SampleRate)
+		if frequency < p.MinFrequency {
+			break
+		}
+
+		vibrato := 1.0
+		if p.VibratoDepth > 0 {
+			vibrato += p.VibratoDepth * math.Sin(2*math.Pi*p.VibratoSpeed*t)
+		}
+
+		phase += frequency * vibrato / SampleRate
+		if phase >= 1 {
+			phase -= math.Floor(phase)
+			noise = rng.Float64()*2 - 1
+		}
+
+		value := wave(p, phase, noise)
+		if p.Noise > 0 {
+			value = value*(1-p.Noise) + (rng.Float64()*2-1)*p.Noise
+		}
+
+		value *= envelope(p, t) * p.Volume
+		value = math.Max(-1, math.Min(1, value))
+		samples = append(samples, int16(value*math.MaxInt16))
+	}
+
+	return samples
+}
+
+func wave(p *Params, phase, noise float64) float64 {
+	switch p.Wave {
+	case Sawtooth:
+		return 1 - 2*phase
+	case Sine:
+		return math.Sin(2 * math.Pi * phase)
+	case Noise:
+		return noise
+	}
+
+	if phase < p.DutyCycle {
+		return 1
+	}
+	return -1
+}
+
+// envelope ramps up during Attack, starts Sustain boosted by Punch and fades
+// out during Decay.
+func envelope(p *Params, t float64) float64 {
+	switch {
+	case t < p.Attack:
+		return t / p.Attack
+	case t < p.Attack+p.Sustain:
+		return 1 + p.Punch*(1-(t-p.Attack)/p.Sustain)
+	case p.Decay == 0:
+		return 0
+	default:
+		return math.Max(0, 1-(t-p.Attack-p.Sustain)/p.Decay)
+	}
+}
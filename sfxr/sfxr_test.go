@@ -0,0 +1,219 @@
+package sfxr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSynthesizeIsDeterministic(t *testing.T) {
+	params, err := LoadParams(os.DirFS("../assets/data/sfxr"), "hit.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := Render(params), Render(params)
+	if !bytes.Equal(first, second) {
+		t.Fatal("the same preset rendered two different sounds")
+	}
+
+	other := *params
+	other.Seed++
+	if bytes.Equal(first, Render(&other)) {
+		t.Error("changing the seed of a noisy preset did not change the sound")
+	}
+}
+
+func TestWAVHeader(t *testing.T) {
+	samples := []int16{0, 1000, -1000, 32767, -32768}
+
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, samples); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if len(data) != 44+len(samples)*2 {
+		t.Fatalf("file is %d bytes, want %d", len(data), 44+len(samples)*2)
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		want   any
+	}{
+		{"riff size", 4, uint32(36 + len(samples)*2)},
+		{"fmt size", 16, uint32(16)},
+		{"format", 20, uint16(1)},
+		{"channels", 22, uint16(1)},
+		{"sample rate", 24, uint32(SampleRate)},
+		{"byte rate", 28, uint32(SampleRate * 2)},
+		{"block align", 32, uint16(2)},
+		{"bits per sample", 34, uint16(16)},
+		{"data size", 40, uint32(len(samples) * 2)},
+	}
+	for _, tag := range []struct {
+		offset int
+		want   string
+	}{{0, "RIFF"}, {8, "WAVE"}, {12, "fmt "}, {36, "data"}} {
+		if got := string(data[tag.offset : tag.offset+4]); got != tag.want {
+			t.Errorf("bytes %d-%d = %q, want %q", tag.offset, tag.offset+3, got, tag.want)
+		}
+	}
+	for _, test := range tests {
+		var got any
+		switch test.want.(type) {
+		case uint16:
+			got = binary.LittleEndian.Uint16(data[test.offset:])
+		case uint32:
+			got = binary.LittleEndian.Uint32(data[test.offset:])
+		}
+		if got != test.want {
+			t.Errorf("%s = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if got := int16(binary.LittleEndian.Uint16(data[44+3*2:])); got != 32767 {
+		t.Errorf("fourth sample = %d, want 32767", got)
+	}
+}
+
+func TestLoadParamsValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		valid  bool
+	}{
+		{name: "defaults", preset: `{}`, valid: true},
+		{name: "no decay", preset: `{"Decay": 0}`, valid: true},
+		{name: "negative attack", preset: `{"Attack": -1}`},
+		{name: "negative sustain", preset: `{"Sustain": -0.1}`},
+		{name: "negative decay", preset: `{"Decay": -0.2}`},
+		{name: "too long", preset: `{"Sustain": 1e9}`},
+		{name: "no frequency", preset: `{"Frequency": 0}`},
+		{name: "negative frequency", preset: `{"Frequency": -440}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := LoadParams(fstest.MapFS{"preset.json": {Data: []byte(test.preset)}}, "preset.json")
+			if (err == nil) != test.valid {
+				t.Fatalf("error = %v, want valid = %v", err, test.valid)
+			}
+			if err == nil {
+				Synthesize(params)
+			}
+		})
+	}
+}
+
+// steady is a sound without envelope or slide, 100 samples per period.
+func steady(wave Wave) *Params {
+	return &Params{Wave: wave, Volume: 0.5, Sustain: 0.1, Frequency: SampleRate / 100, DutyCycle: 0.25}
+}
+
+func TestEnvelopeLength(t *testing.T) {
+	params := &Params{Wave: Square, Volume: 0.5, Attack: 0.25, Sustain: 0.25, Decay: 0.5, Frequency: 440, DutyCycle: 0.5}
+
+	if got := len(Synthesize(params)); got != SampleRate {
+		t.Errorf("%d samples, want %d", got, SampleRate)
+	}
+}
+
+func TestMinFrequencyStopsEarly(t *testing.T) {
+	params := &Params{Wave: Sine, Volume: 0.5, Sustain: 1, Frequency: 440, MinFrequency: 220, Slide: -4}
+
+	// Falling 4 octaves per second, the pitch halves after a quarter second.
+	want := SampleRate / 4
+	if got := len(Synthesize(params)); math.Abs(float64(got-want)) > 10 {
+		t.Errorf("%d samples, want about %d", got, want)
+	}
+}
+
+func TestSlideDirection(t *testing.T) {
+	crossings := func(samples []int16) int {
+		count := 0
+		for i := 1; i < len(samples); i++ {
+			if (samples[i-1] < 0) != (samples[i] < 0) {
+				count++
+			}
+		}
+		return count
+	}
+
+	for _, test := range []struct {
+		slide  float64
+		rising bool
+	}{{slide: 2, rising: true}, {slide: -2, rising: false}} {
+		params := &Params{Wave: Sine, Volume: 0.5, Sustain: 1, Frequency: 440, Slide: test.slide}
+		samples := Synthesize(params)
+		quarter := len(samples) / 4
+
+		first, last := crossings(samples[:quarter]), crossings(samples[len(samples)-quarter:])
+		if (last > first) != test.rising {
+			t.Errorf("slide %v: %d crossings in the first quarter, %d in the last", test.slide, first, last)
+		}
+	}
+}
+
+func TestWaveShapes(t *testing.T) {
+	amplitude := int16(math.MaxInt16 / 2)
+
+	t.Run("square", func(t *testing.T) {
+		high := 0
+		for i, sample := range Synthesize(steady(Square)) {
+			if sample != amplitude && sample != -amplitude {
+				t.Fatalf("sample %d = %d, want ±%d", i, sample, amplitude)
+			}
+			if sample > 0 {
+				high++
+			}
+		}
+		// Rounding can move the edge of each period by a sample.
+		if want := 4410 / 4; math.Abs(float64(high-want)) > 4410/100 {
+			t.Errorf("%d high samples, want a quarter of them with a 0.25 duty cycle", high)
+		}
+	})
+
+	t.Run("sawtooth", func(t *testing.T) {
+		samples := Synthesize(steady(Sawtooth))
+		rises := 0
+		for i := 1; i < len(samples); i++ {
+			if samples[i] > samples[i-1] {
+				rises++
+			}
+		}
+		if periods := len(samples) / 100; rises > periods+1 {
+			t.Errorf("the wave rose %d times over %d periods, want only when it wraps", rises, periods)
+		}
+	})
+
+	t.Run("sine", func(t *testing.T) {
+		for i, sample := range Synthesize(steady(Sine)) {
+			want := 0.5 * math.MaxInt16 * math.Sin(2*math.Pi*float64(i+1)/100)
+			if math.Abs(float64(sample)-want) > 2 {
+				t.Fatalf("sample %d = %d, want %.0f", i, sample, want)
+			}
+		}
+	})
+
+	t.Run("noise", func(t *testing.T) {
+		samples := Synthesize(steady(Noise))
+		changes, negative := 0, false
+		for i := 1; i < len(samples); i++ {
+			if samples[i] != samples[i-1] {
+				changes++
+			}
+			negative = negative || samples[i] < 0
+		}
+		if periods := len(samples) / 100; changes < periods-1 || changes > periods+1 {
+			t.Errorf("noise changed %d times over %d periods, want once per period", changes, periods)
+		}
+		if !negative {
+			t.Error("noise is never negative")
+		}
+	})
+}
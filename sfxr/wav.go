@@ -0,0 +1,40 @@
+package sfxr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// WriteWAV writes mono samples as a 16 bit PCM WAV file, the format of the
+// sounds in assets/data/sfx.
+func WriteWAV(w io.Writer, samples []int16) error {
+	const (
+		channels      = 1
+		bitsPerSample = 16
+		blockAlign    = channels * bitsPerSample / 8
+	)
+	dataSize := uint32(len(samples) * blockAlign)
+
+	header := []any{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(channels),
+		uint32(SampleRate), uint32(SampleRate * blockAlign), uint16(blockAlign), uint16(bitsPerSample),
+		[]byte("data"), dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	return binary.Write(w, binary.LittleEndian, samples)
+}
+
+// Render synthesizes params straight into the bytes of a WAV file.
+func Render(p *Params) []byte {
+	var buf bytes.Buffer
+	WriteWAV(&buf, Synthesize(p))
+
+	return buf.Bytes()
+}
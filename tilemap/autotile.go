@@ -0,0 +1,165 @@
+package tilemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"strings"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+// AutoTilePath is relative to assets.FS.
+const AutoTilePath = "autotile.json"
+
+// AutoTileRules maps, per tile type, the neighbours of a tile to the variant
+// it should use. Only neighbours of the same type count, written as letters
+// out of "URDL" for up, right, down and left, such as "RD" for a top left
+// corner. Tiles whose neighbours have no rule keep their variant.
+type AutoTileRules map[string]map[string]int
+
+// AutoTiles are the rules AutoTile applies.
+var AutoTiles = DefaultAutoTileRules()
+
+// autoTileDirections are in "URDL" order, bit i of a mask standing for the
+// neighbour at autoTileDirections[i].
+var autoTileDirections = []types.Vector{
+	{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0},
+}
+
+func DefaultAutoTileRules() AutoTileRules {
+	edges := map[string]int{
+		"RD":   0,
+		"RDL":  1,
+		"DL":   2,
+		"UDL":  3,
+		"UL":   4,
+		"URL":  5,
+		"UR":   6,
+		"URD":  7,
+		"URDL": 8,
+	}
+
+	return AutoTileRules{
+		"grass": edges,
+		"stone": edges,
+	}
+}
+
+func LoadAutoTileRules(fsys fs.FS, path string) (AutoTileRules, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := AutoTileRules{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for tileType, variants := range rules {
+		for neighbours := range variants {
+			if _, err := neighbourMask(neighbours); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, tileType, err)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func neighbourMask(neighbours string) (int, error) {
+	mask := 0
+	for i := 0; i < len(neighbours); i++ {
+		index := strings.IndexByte("URDL", neighbours[i])
+		if index < 0 {
+			return 0, fmt.Errorf("%q: unknown direction %q", neighbours, neighbours[i])
+		}
+		mask |= 1 << index
+	}
+
+	return mask, nil
+}
+
+// Region is a rectangle of the grid, in tiles, edges included.
+type Region struct {
+	Left   int
+	Top    int
+	Right  int
+	Bottom int
+}
+
+// Bounds returns the region holding every grid tile of the map. A map without
+// grid tiles gives an empty region, with Right and Bottom below Left and Top.
+func (t *TileMapType) Bounds() Region {
+	if t.TileCount() == 0 {
+		return Region{Right: -1, Bottom: -1}
+	}
+
+	bounds := Region{Left: math.MaxInt, Top: math.MaxInt, Right: math.MinInt, Bottom: math.MinInt}
+	for _, layer := range t.Layers {
+		for _, tile := range layer.allTiles() {
+			x, y := int(tile.Position.X), int(tile.Position.Y)
+			if x < bounds.Left {
+				bounds.Left = x
+			}
+			if x > bounds.Right {
+				bounds.Right = x
+			}
+			if y < bounds.Top {
+				bounds.Top = y
+			}
+			if y > bounds.Bottom {
+				bounds.Bottom = y
+			}
+		}
+	}
+
+	return bounds
+}
+
+// AutoTile picks the variant of every tile of region, on every layer, from
+// AutoTiles. It returns how many tiles changed.
+func (t *TileMapType) AutoTile(region Region) int {
+	masks := map[string]map[int]int{}
+	for tileType, variants := range AutoTiles {
+		masks[tileType] = map[int]int{}
+		for neighbours, variant := range variants {
+			if mask, err := neighbourMask(neighbours); err == nil {
+				masks[tileType][mask] = variant
+			}
+		}
+	}
+
+	changed := 0
+	for _, layer := range t.Layers {
+		updates := []Tile{}
+		layer.TilesInRange(region.Left, region.Top, region.Right, region.Bottom, func(tile Tile) {
+			variants, ok := masks[tile.Type]
+			if !ok {
+				return
+			}
+
+			x, y := int(tile.Position.X), int(tile.Position.Y)
+			mask := 0
+			for index, direction := range autoTileDirections {
+				if neighbour, ok := layer.TileAt(x+int(direction.X), y+int(direction.Y)); ok && neighbour.Type == tile.Type {
+					mask |= 1 << index
+				}
+			}
+
+			if variant, ok := variants[mask]; ok && variant != tile.Variant {
+				tile.Variant = variant
+				updates = append(updates, tile)
+			}
+		})
+
+		for _, tile := range updates {
+			layer.SetTile(tile)
+		}
+		changed += len(updates)
+	}
+
+	return changed
+}
@@ -0,0 +1,146 @@
+package tilemap
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+func useShippedAutoTiles(t *testing.T) {
+	t.Helper()
+
+	rules, err := LoadAutoTileRules(os.DirFS("../assets/data"), AutoTilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := AutoTiles
+	AutoTiles = rules
+	t.Cleanup(func() { AutoTiles = saved })
+}
+
+// autoTileMap returns a map with a 3x3 block of grass at 0;0 on its solid
+// layer, every tile on variant.
+func autoTileMap(variant int) *TileMapType {
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			tileMap.Layer("solid").SetTile(Tile{Position: types.Vector{X: float64(x), Y: float64(y)}, Type: "grass", Variant: variant})
+		}
+	}
+
+	return tileMap
+}
+
+func variantAt(t *testing.T, layer *Layer, x, y int) int {
+	t.Helper()
+
+	tile, ok := layer.TileAt(x, y)
+	if !ok {
+		t.Fatalf("no tile at %d;%d", x, y)
+	}
+
+	return tile.Variant
+}
+
+func TestAutoTileBlock(t *testing.T) {
+	useShippedAutoTiles(t)
+	tileMap := autoTileMap(0)
+
+	changed := tileMap.AutoTile(tileMap.Bounds())
+
+	tests := []struct {
+		x, y       int
+		neighbours string
+		want       int
+	}{
+		{0, 0, "RD", 0},
+		{1, 0, "RDL", 1},
+		{2, 0, "DL", 2},
+		{2, 1, "UDL", 3},
+		{2, 2, "UL", 4},
+		{1, 2, "URL", 5},
+		{0, 2, "UR", 6},
+		{0, 1, "URD", 7},
+		{1, 1, "URDL", 8},
+	}
+	for _, test := range tests {
+		if got := variantAt(t, tileMap.Layer("solid"), test.x, test.y); got != test.want {
+			t.Errorf("%d;%d with %s neighbours: variant %d, want %d", test.x, test.y, test.neighbours, got, test.want)
+		}
+	}
+
+	// The top left corner already had variant 0.
+	if changed != 8 {
+		t.Errorf("%d tiles changed, want 8", changed)
+	}
+	if again := tileMap.AutoTile(tileMap.Bounds()); again != 0 {
+		t.Errorf("%d tiles changed the second time, want 0", again)
+	}
+}
+
+func TestAutoTileRegion(t *testing.T) {
+	useShippedAutoTiles(t)
+	tileMap := autoTileMap(99)
+
+	changed := tileMap.AutoTile(Region{Left: 1, Top: 1, Right: 2, Bottom: 1})
+
+	if changed != 2 {
+		t.Errorf("%d tiles changed, want 2", changed)
+	}
+	solid := tileMap.Layer("solid")
+	if got := variantAt(t, solid, 1, 1); got != 8 {
+		t.Errorf("center variant = %d, want 8", got)
+	}
+	if got := variantAt(t, solid, 2, 1); got != 3 {
+		t.Errorf("right edge variant = %d, want 3", got)
+	}
+	for _, position := range [][2]int{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		if got := variantAt(t, solid, position[0], position[1]); got != 99 {
+			t.Errorf("%d;%d outside the region changed to %d", position[0], position[1], got)
+		}
+	}
+}
+
+func TestAutoTileIgnoresOtherTypesAndLayers(t *testing.T) {
+	useShippedAutoTiles(t)
+
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+	solid := tileMap.Layer("solid")
+	for x := 0; x < 3; x++ {
+		solid.SetTile(Tile{Position: types.Vector{X: float64(x)}, Type: "grass", Variant: 99})
+	}
+	// Under the middle grass, stone on the same layer and grass on another.
+	solid.SetTile(Tile{Position: types.Vector{X: 1, Y: 1}, Type: "stone", Variant: 99})
+	tileMap.Layer("background").SetTile(Tile{Position: types.Vector{X: 1, Y: -1}, Type: "grass", Variant: 99})
+
+	tileMap.AutoTile(tileMap.Bounds())
+
+	// With only R and L counting, there is no rule and the variant stays.
+	if got := variantAt(t, solid, 1, 0); got != 99 {
+		t.Errorf("middle grass variant = %d, want 99", got)
+	}
+	if got := variantAt(t, solid, 0, 0); got != 99 {
+		t.Errorf("left grass variant = %d, want 99", got)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+
+	empty := tileMap.Bounds()
+	if empty.Right >= empty.Left || empty.Bottom >= empty.Top {
+		t.Errorf("bounds of an empty map = %+v, want an empty region", empty)
+	}
+	if changed := tileMap.AutoTile(empty); changed != 0 {
+		t.Errorf("%d tiles changed on an empty map", changed)
+	}
+
+	tileMap.Layer("solid").SetTile(Tile{Position: types.Vector{X: -4, Y: 2}, Type: "grass"})
+	tileMap.Layer("foreground").SetTile(Tile{Position: types.Vector{X: 7, Y: -3}, Type: "decor"})
+
+	if got, want := tileMap.Bounds(), (Region{Left: -4, Top: -3, Right: 7, Bottom: 2}); got != want {
+		t.Errorf("bounds = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package tilemap
+
+// ChunkSize is the width and height, in tiles, of the chunks grid tiles are
+// stored in.
+const ChunkSize = 16
+
+type chunkKey struct {
+	X int
+	Y int
+}
+
+// chunk is a fixed square of the grid. Looking a tile up is two integer
+// divisions and an array index, without the string keys the map format uses.
+type chunk struct {
+	Tiles [ChunkSize * ChunkSize]Tile
+	Set   [ChunkSize * ChunkSize]bool
+	Count int
+}
+
+func chunkLocation(x, y int) (chunkKey, int) {
+	key := chunkKey{X: floorDiv(x, ChunkSize), Y: floorDiv(y, ChunkSize)}
+	index := (y-key.Y*ChunkSize)*ChunkSize + (x - key.X*ChunkSize)
+
+	return key, index
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+
+	return a / b
+}
+
+func clampInChunk(offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset >= ChunkSize {
+		return ChunkSize - 1
+	}
+
+	return offset
+}
+
+// TileAt returns the grid tile at x, y, in tiles.
+func (l *Layer) TileAt(x, y int) (Tile, bool) {
+	key, index := chunkLocation(x, y)
+	c, ok := l.chunks[key]
+	if !ok || !c.Set[index] {
+		return Tile{}, false
+	}
+
+	return c.Tiles[index], true
+}
+
+func (l *Layer) setTileAt(x, y int, tile Tile) {
+	if l.chunks == nil {
+		l.chunks = map[chunkKey]*chunk{}
+	}
+
+	key, index := chunkLocation(x, y)
+	c, ok := l.chunks[key]
+	if !ok {
+		c = &chunk{}
+		l.chunks[key] = c
+	}
+
+	if !c.Set[index] {
+		c.Set[index] = true
+		c.Count++
+	}
+	c.Tiles[index] = tile
+}
+
+func (l *Layer) removeTileAt(x, y int) {
+	key, index := chunkLocation(x, y)
+	c, ok := l.chunks[key]
+	if !ok || !c.Set[index] {
+		return
+	}
+
+	c.Tiles[index] = Tile{}
+	c.Set[index] = false
+	c.Count--
+	if c.Count == 0 {
+		delete(l.chunks, key)
+	}
+}
+
+// TilesInRange calls fn for every grid tile from left, top to right, bottom
+// included, in tiles, chunk by chunk and column by column within a chunk.
+// Only the chunks overlapping the range are visited, each of them once.
+func (l *Layer) TilesInRange(left, top, right, bottom int, fn func(tile Tile)) {
+	topLeft, _ := chunkLocation(left, top)
+	bottomRight, _ := chunkLocation(right, bottom)
+
+	for chunkX := topLeft.X; chunkX <= bottomRight.X; chunkX++ {
+		startX, endX := clampInChunk(left-chunkX*ChunkSize), clampInChunk(right-chunkX*ChunkSize)
+		for chunkY := topLeft.Y; chunkY <= bottomRight.Y; chunkY++ {
+			c, ok := l.chunks[chunkKey{X: chunkX, Y: chunkY}]
+			if !ok {
+				continue
+			}
+
+			startY, endY := clampInChunk(top-chunkY*ChunkSize), clampInChunk(bottom-chunkY*ChunkSize)
+			for x := startX; x <= endX; x++ {
+				for y := startY; y <= endY; y++ {
+					if index := y*ChunkSize + x; c.Set[index] {
+						fn(c.Tiles[index])
+					}
+				}
+			}
+		}
+	}
+}
+
+// TileCount returns how many grid tiles the layer holds.
+func (l *Layer) TileCount() int {
+	count := 0
+	for _, c := range l.chunks {
+		count += c.Count
+	}
+
+	return count
+}
+
+func (l *Layer) allTiles() []Tile {
+	tiles := make([]Tile, 0, l.TileCount())
+	for _, c := range l.chunks {
+		for index, set := range c.Set {
+			if set {
+				tiles = append(tiles, c.Tiles[index])
+			}
+		}
+	}
+
+	return tiles
+}
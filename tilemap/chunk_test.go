@@ -0,0 +1,181 @@
+package tilemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct{ a, want int }{
+		{0, 0},
+		{15, 0},
+		{16, 1},
+		{31, 1},
+		{-1, -1},
+		{-16, -1},
+		{-17, -2},
+		{-32, -2},
+	}
+
+	for _, test := range tests {
+		if got := floorDiv(test.a, ChunkSize); got != test.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", test.a, ChunkSize, got, test.want)
+		}
+	}
+}
+
+func TestChunkLocation(t *testing.T) {
+	tests := []struct {
+		x, y  int
+		key   chunkKey
+		index int
+	}{
+		{0, 0, chunkKey{0, 0}, 0},
+		{15, 15, chunkKey{0, 0}, ChunkSize*ChunkSize - 1},
+		{16, 0, chunkKey{1, 0}, 0},
+		{-1, -1, chunkKey{-1, -1}, ChunkSize*ChunkSize - 1},
+		{-16, -16, chunkKey{-1, -1}, 0},
+		{-17, 3, chunkKey{-2, 0}, 3*ChunkSize + 15},
+	}
+
+	for _, test := range tests {
+		key, index := chunkLocation(test.x, test.y)
+		if key != test.key || index != test.index {
+			t.Errorf("chunkLocation(%d, %d) = %v, %d, want %v, %d", test.x, test.y, key, index, test.key, test.index)
+		}
+	}
+}
+
+func TestTilesInRangeAcrossChunks(t *testing.T) {
+	layer := NewLayer("solid", 0, true)
+	for x := -20; x <= 20; x++ {
+		for y := -1; y <= 0; y++ {
+			layer.SetTile(Tile{Position: types.Vector{X: float64(x), Y: float64(y)}, Type: "stone"})
+		}
+	}
+
+	tests := []struct {
+		name                     string
+		left, top, right, bottom int
+		want                     int
+	}{
+		{name: "everything", left: -20, top: -1, right: 20, bottom: 0, want: 82},
+		{name: "one chunk border", left: -1, top: 0, right: 0, bottom: 0, want: 2},
+		{name: "two chunk borders", left: -17, top: -1, right: 16, bottom: -1, want: 34},
+		{name: "single negative tile", left: -16, top: -1, right: -16, bottom: -1, want: 1},
+		{name: "empty chunk", left: -5, top: 20, right: 5, bottom: 40, want: 0},
+		{name: "inverted", left: 5, top: 0, right: -5, bottom: 0, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			layer.TilesInRange(test.left, test.top, test.right, test.bottom, func(tile Tile) {
+				x, y := int(tile.Position.X), int(tile.Position.Y)
+				if x < test.left || x > test.right || y < test.top || y > test.bottom {
+					t.Errorf("tile %d;%d is out of the range", x, y)
+				}
+				if seen[tileKey(tile)] {
+					t.Errorf("tile %d;%d visited twice", x, y)
+				}
+				seen[tileKey(tile)] = true
+			})
+
+			if len(seen) != test.want {
+				t.Errorf("%d tiles, want %d", len(seen), test.want)
+			}
+		})
+	}
+}
+
+func TestRemoveTileDeletesEmptyChunk(t *testing.T) {
+	layer := NewLayer("solid", 0, true)
+	first := Tile{Position: types.Vector{X: -5, Y: -5}, Type: "stone"}
+	second := Tile{Position: types.Vector{X: -6, Y: -5}, Type: "stone"}
+	layer.SetTile(first)
+	layer.SetTile(second)
+
+	layer.RemoveTile(first.Position)
+	if len(layer.chunks) != 1 {
+		t.Fatalf("%d chunks after removing one of two tiles, want 1", len(layer.chunks))
+	}
+	if _, ok := layer.TileAt(-5, -5); ok {
+		t.Error("removed tile is still there")
+	}
+
+	layer.RemoveTile(second.Position)
+	if len(layer.chunks) != 0 {
+		t.Errorf("%d chunks after removing every tile, want 0", len(layer.chunks))
+	}
+	if layer.TileCount() != 0 {
+		t.Errorf("tile count = %d, want 0", layer.TileCount())
+	}
+
+	layer.RemoveTile(second.Position)
+	if layer.TileCount() != 0 {
+		t.Errorf("removing a missing tile changed the count to %d", layer.TileCount())
+	}
+}
+
+// TestShippedMapRoundTrip loads map 0, saved in the format from before chunks
+// and layers, and checks every tile survives being saved and loaded again.
+func TestShippedMapRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../assets/data/maps/0.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var legacy tileMapJSON
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&legacy); err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy.Tiles) == 0 {
+		t.Fatal("map 0 has no tiles in the old format")
+	}
+
+	tileMap := &TileMapType{}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(tileMap); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := json.Marshal(tileMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &TileMapType{}
+	if err := json.Unmarshal(saved, reloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for _, layer := range reloaded.Layers {
+		count += layer.TileCount()
+	}
+	if count != len(legacy.Tiles) {
+		t.Errorf("%d tiles after the round trip, want %d", count, len(legacy.Tiles))
+	}
+
+	for key, want := range legacy.Tiles {
+		var x, y int
+		if _, err := fmt.Sscanf(key, "%d;%d", &x, &y); err != nil {
+			t.Fatalf("key %q: %v", key, err)
+		}
+		if key != tileKey(want) {
+			t.Errorf("key %q does not match the tile position %v", key, want.Position)
+		}
+
+		found := false
+		for _, layer := range reloaded.Layers {
+			if tile, ok := layer.TileAt(x, y); ok {
+				found = tile == want
+			}
+		}
+		if !found {
+			t.Errorf("tile %s = %+v is missing after the round trip", key, want)
+		}
+	}
+}
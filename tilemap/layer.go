@@ -0,0 +1,166 @@
+package tilemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/types"
+)
+
+// Layers are drawn by increasing Z. Those with a Z above ForegroundZ are
+// drawn over the entities, see TileMapType.DrawForeground.
+const ForegroundZ = 0
+
+// Layer is one grid of tiles plus its off-grid tiles. Parallax scales how
+// much the layer moves with the camera, 1 moving with the world. Colliding
+// layers must keep a Parallax of 1, or they would be drawn away from where
+// they collide. Opacity goes from 0 to 1.
+type Layer struct {
+	Name         string
+	Z            int
+	Parallax     float64
+	Opacity      float64
+	Collides     bool
+	OffGridTiles []Tile
+	chunks       map[chunkKey]*chunk
+}
+
+func NewLayer(name string, z int, collides bool) *Layer {
+	return &Layer{
+		Name:     name,
+		Z:        z,
+		Parallax: 1,
+		Opacity:  1,
+		Collides: collides,
+	}
+}
+
+// DefaultLayers are the layers of a new map, and the ones older maps without
+// layers are split into.
+func DefaultLayers() []*Layer {
+	return []*Layer{
+		NewLayer("background", -1, false),
+		NewLayer("solid", 0, true),
+		NewLayer("foreground", 1, false),
+	}
+}
+
+func (l *Layer) SetTile(tile Tile) {
+	l.setTileAt(int(tile.Position.X), int(tile.Position.Y), tile)
+}
+
+func (l *Layer) RemoveTile(position types.Vector) {
+	l.removeTileAt(int(position.X), int(position.Y))
+}
+
+func (l *Layer) SetOffGridTile(tile Tile) {
+	l.OffGridTiles = append(l.OffGridTiles, tile)
+}
+
+func (l *Layer) RemoveOffGridTile(tile Tile) {
+	for i, offGridTile := range l.OffGridTiles {
+		if offGridTile.Position == tile.Position {
+			l.OffGridTiles = append(l.OffGridTiles[:i], l.OffGridTiles[i+1:]...)
+			break
+		}
+	}
+}
+
+// Scroll returns the camera position as seen by the layer, with its parallax
+// applied.
+func (l *Layer) Scroll(scrollX, scrollY int) (int, int) {
+	return int(float64(scrollX) * l.Parallax), int(float64(scrollY) * l.Parallax)
+}
+
+func (l *Layer) Draw(screen *ebiten.Image, tileSize, scrollX, scrollY int, renderContext string) {
+	scrollX, scrollY = l.Scroll(scrollX, scrollY)
+
+	drawTile := func(tile Tile) {
+		var shouldRender bool
+		if renderContext == "game" {
+			shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnGame
+		} else if renderContext == "editor" {
+			shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnEditor
+		}
+
+		if shouldRender {
+			options := &ebiten.DrawImageOptions{}
+			options.GeoM.Translate(tile.Position.X*float64(tileSize)-float64(scrollX), tile.Position.Y*float64(tileSize)-float64(scrollY))
+			options.ColorScale.ScaleAlpha(float32(l.Opacity))
+			screen.DrawImage(assets.Assets.Images[tile.Type].Image[tile.Variant], options)
+		}
+	}
+
+	for _, tile := range l.OffGridTiles {
+		drawTile(tile)
+	}
+
+	screenWidth := screen.Bounds().Max.X
+	screenHeight := screen.Bounds().Max.Y
+	left, top := floorDiv(scrollX, tileSize), floorDiv(scrollY, tileSize)
+	right, bottom := floorDiv(scrollX+screenWidth, tileSize), floorDiv(scrollY+screenHeight, tileSize)
+
+	l.TilesInRange(left, top, right, bottom, drawTile)
+}
+
+// layerJSON is how a layer is saved, grid tiles keyed by "x;y".
+type layerJSON struct {
+	Name         string
+	Z            int
+	Parallax     float64
+	Opacity      float64
+	Collides     bool
+	Tiles        map[string]Tile
+	OffGridTiles []Tile
+}
+
+func (l *Layer) MarshalJSON() ([]byte, error) {
+	tiles := map[string]Tile{}
+	for _, tile := range l.allTiles() {
+		tiles[tileKey(tile)] = tile
+	}
+
+	return json.Marshal(layerJSON{
+		Name:         l.Name,
+		Z:            l.Z,
+		Parallax:     l.Parallax,
+		Opacity:      l.Opacity,
+		Collides:     l.Collides,
+		Tiles:        tiles,
+		OffGridTiles: l.OffGridTiles,
+	})
+}
+
+func (l *Layer) UnmarshalJSON(data []byte) error {
+	saved := layerJSON{
+		Parallax: 1,
+		Opacity:  1,
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	if saved.Collides && saved.Parallax != 1 {
+		return fmt.Errorf("layer %q collides but has a parallax of %v, only 1 is allowed", saved.Name, saved.Parallax)
+	}
+
+	*l = Layer{
+		Name:         saved.Name,
+		Z:            saved.Z,
+		Parallax:     saved.Parallax,
+		Opacity:      saved.Opacity,
+		Collides:     saved.Collides,
+		OffGridTiles: saved.OffGridTiles,
+	}
+	for _, tile := range saved.Tiles {
+		l.SetTile(tile)
+	}
+
+	return nil
+}
+
+func tileKey(tile Tile) string {
+	return strconv.Itoa(int(tile.Position.X)) + ";" + strconv.Itoa(int(tile.Position.Y))
+}
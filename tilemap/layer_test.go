@@ -0,0 +1,117 @@
+package tilemap
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+func TestLayerDefaults(t *testing.T) {
+	layer := &Layer{}
+	if err := json.Unmarshal([]byte(`{"Name": "hills", "Z": -2}`), layer); err != nil {
+		t.Fatal(err)
+	}
+
+	if layer.Parallax != 1 {
+		t.Errorf("parallax = %v, want 1", layer.Parallax)
+	}
+	if layer.Opacity != 1 {
+		t.Errorf("opacity = %v, want 1", layer.Opacity)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Name": "hills", "Parallax": 0.5, "Opacity": 0}`), layer); err != nil {
+		t.Fatal(err)
+	}
+	if layer.Parallax != 0.5 || layer.Opacity != 0 {
+		t.Errorf("parallax, opacity = %v, %v, want the saved 0.5, 0", layer.Parallax, layer.Opacity)
+	}
+}
+
+func TestCollidingLayerParallax(t *testing.T) {
+	layer := &Layer{}
+	if err := json.Unmarshal([]byte(`{"Name": "solid", "Collides": true, "Parallax": 0.5}`), layer); err == nil {
+		t.Error("a colliding layer with a parallax was accepted")
+	}
+	if err := json.Unmarshal([]byte(`{"Name": "solid", "Collides": true}`), layer); err != nil {
+		t.Errorf("a colliding layer without parallax was refused: %v", err)
+	}
+}
+
+func layerNames(layers []*Layer) []string {
+	names := []string{}
+	for _, layer := range layers {
+		names = append(names, layer.Name)
+	}
+
+	return names
+}
+
+func TestLayerOrder(t *testing.T) {
+	data := []byte(`{"TileSize": 16, "Layers": [
+		{"Name": "foreground", "Z": 1},
+		{"Name": "solid", "Z": 0, "Collides": true},
+		{"Name": "sky", "Z": -5, "Parallax": 0.2},
+		{"Name": "background", "Z": -1},
+		{"Name": "mist", "Z": 1}
+	]}`)
+
+	tileMap := &TileMapType{}
+	if err := json.Unmarshal(data, tileMap); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := layerNames(tileMap.Layers), []string{"sky", "background", "solid", "foreground", "mist"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("layers = %v, want %v", got, want)
+	}
+	if got, want := layerNames(tileMap.layersInFront(false)), []string{"sky", "background", "solid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("background layers = %v, want %v", got, want)
+	}
+	if got, want := layerNames(tileMap.layersInFront(true)), []string{"foreground", "mist"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("foreground layers = %v, want %v", got, want)
+	}
+
+	tileMap.AddLayer(NewLayer("clouds", -3, false))
+	if got, want := layerNames(tileMap.Layers), []string{"sky", "clouds", "background", "solid", "foreground", "mist"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("layers after AddLayer = %v, want %v", got, want)
+	}
+}
+
+func TestLayersSaveLoad(t *testing.T) {
+	sky := NewLayer("sky", -5, false)
+	sky.Parallax = 0.25
+	sky.Opacity = 0.5
+	sky.SetTile(Tile{Position: types.Vector{X: 40, Y: -2}, Type: "decor", Variant: 1})
+	sky.SetOffGridTile(Tile{Position: types.Vector{X: 1.5, Y: 2.25}, Type: "large_decor"})
+
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+	tileMap.AddLayer(sky)
+	tileMap.Layer("solid").SetTile(Tile{Position: types.Vector{X: 3, Y: 4}, Type: "grass", Variant: 5})
+
+	path := filepath.Join(t.TempDir(), "map.json")
+	if err := tileMap.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &TileMapType{}
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := layerNames(loaded.Layers), layerNames(tileMap.Layers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("layers = %v, want %v", got, want)
+	}
+	for i, want := range tileMap.Layers {
+		got := loaded.Layers[i]
+		if got.Z != want.Z || got.Parallax != want.Parallax || got.Opacity != want.Opacity || got.Collides != want.Collides {
+			t.Errorf("layer %s = %+v, want %+v", want.Name, got, want)
+		}
+		if !reflect.DeepEqual(sortTiles(got.allTiles()), sortTiles(want.allTiles())) {
+			t.Errorf("layer %s tiles = %v, want %v", want.Name, got.allTiles(), want.allTiles())
+		}
+		if !reflect.DeepEqual(got.OffGridTiles, want.OffGridTiles) {
+			t.Errorf("layer %s off-grid tiles = %v, want %v", want.Name, got.OffGridTiles, want.OffGridTiles)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tilemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// TileTypesPath is relative to assets.FS.
+const TileTypesPath = "tiletypes.json"
+
+// AnyVariant is the variant of registry entries covering a whole type.
+const AnyVariant = -1
+
+// TileProperties are the physical properties of a tile. A OneWay tile is
+// only solid for bodies falling on it from above. Damage is taken by the
+// player when touching the tile, Friction scales how fast bodies standing on
+// it slow down, 1 being normal ground, and Bounce is the upward speed bodies
+// landing on it are launched at.
+type TileProperties struct {
+	Solid    bool
+	OneWay   bool
+	Damage   int
+	Friction float64
+	Bounce   float64
+}
+
+func DefaultTileProperties() TileProperties {
+	return TileProperties{
+		Friction: 1,
+	}
+}
+
+type TileTypeKey struct {
+	Type    string
+	Variant int
+}
+
+// TileTypeRegistry holds the properties of tile types, and optionally of
+// single variants, which win over their type. Saved as JSON, keys are either
+// a type, "grass", or a type and a variant, "grass:3". Tiles missing from the
+// registry have the default properties and don't collide.
+type TileTypeRegistry map[TileTypeKey]TileProperties
+
+// TileTypes is the registry collisions are checked against.
+var TileTypes = DefaultTileTypes()
+
+func DefaultTileTypes() TileTypeRegistry {
+	solid := DefaultTileProperties()
+	solid.Solid = true
+
+	return TileTypeRegistry{
+		{Type: "grass", Variant: AnyVariant}: solid,
+		{Type: "stone", Variant: AnyVariant}: solid,
+	}
+}
+
+func LoadTileTypes(fsys fs.FS, path string) (TileTypeRegistry, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	registry := TileTypeRegistry{}
+	for name, entry := range entries {
+		key := TileTypeKey{Type: name, Variant: AnyVariant}
+		if tileType, variant, ok := strings.Cut(name, ":"); ok {
+			key.Type = tileType
+			if key.Variant, err = strconv.Atoi(variant); err != nil || key.Variant < 0 {
+				return nil, fmt.Errorf("%s: %q: bad variant", path, name)
+			}
+		}
+
+		properties := DefaultTileProperties()
+		if err := json.Unmarshal(entry, &properties); err != nil {
+			return nil, fmt.Errorf("%s: %q: %w", path, name, err)
+		}
+		registry[key] = properties
+	}
+
+	return registry, nil
+}
+
+func (r TileTypeRegistry) Properties(tile Tile) TileProperties {
+	if properties, ok := r.Lookup(tile); ok {
+		return properties
+	}
+
+	return DefaultTileProperties()
+}
+
+// Lookup finds the entry of a tile, its variant first and then its type.
+func (r TileTypeRegistry) Lookup(tile Tile) (TileProperties, bool) {
+	if properties, ok := r[TileTypeKey{Type: tile.Type, Variant: tile.Variant}]; ok {
+		return properties, true
+	}
+	properties, ok := r[TileTypeKey{Type: tile.Type, Variant: AnyVariant}]
+
+	return properties, ok
+}
@@ -0,0 +1,98 @@
+package tilemap
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+const testTileTypes = `{
+  "stone": {"Solid": true},
+  "stone:2": {"OneWay": true},
+  "spikes": {"Damage": 2},
+  "ice:0": {"Solid": true, "Friction": 0.05}
+}`
+
+func TestLoadTileTypes(t *testing.T) {
+	registry, err := LoadTileTypes(fstest.MapFS{"types.json": {Data: []byte(testTileTypes)}}, "types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solid := DefaultTileProperties()
+	solid.Solid = true
+	oneWay := DefaultTileProperties()
+	oneWay.OneWay = true
+	spikes := DefaultTileProperties()
+	spikes.Damage = 2
+	ice := TileProperties{Solid: true, Friction: 0.05}
+
+	tests := []struct {
+		name  string
+		tile  Tile
+		want  TileProperties
+		found bool
+	}{
+		{name: "type", tile: Tile{Type: "stone", Variant: 0}, want: solid, found: true},
+		{name: "variant wins over type", tile: Tile{Type: "stone", Variant: 2}, want: oneWay, found: true},
+		{name: "type without variants", tile: Tile{Type: "spikes", Variant: 5}, want: spikes, found: true},
+		{name: "variant", tile: Tile{Type: "ice", Variant: 0}, want: ice, found: true},
+		{name: "other variant", tile: Tile{Type: "ice", Variant: 1}, want: DefaultTileProperties()},
+		{name: "missing type", tile: Tile{Type: "decor", Variant: 0}, want: DefaultTileProperties()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, found := registry.Lookup(test.tile); found != test.found {
+				t.Errorf("found = %v, want %v", found, test.found)
+			}
+			if got := registry.Properties(test.tile); got != test.want {
+				t.Errorf("properties = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadTileTypesBadVariant(t *testing.T) {
+	for _, name := range []string{"stone:", "stone:a", "stone:-1"} {
+		data := []byte(`{"` + name + `": {"Solid": true}}`)
+		if _, err := LoadTileTypes(fstest.MapFS{"types.json": {Data: data}}, "types.json"); err == nil {
+			t.Errorf("%q: no error", name)
+		}
+	}
+}
+
+func TestLegacyLayersKeepRegisteredTiles(t *testing.T) {
+	defer func(saved TileTypeRegistry) { TileTypes = saved }(TileTypes)
+	TileTypes = TileTypeRegistry{
+		{Type: "stone", Variant: AnyVariant}:    {Solid: true, Friction: 1},
+		{Type: "platform", Variant: AnyVariant}: {OneWay: true, Friction: 1},
+		{Type: "spikes", Variant: AnyVariant}:   {Damage: 1, Friction: 1},
+	}
+
+	tiles := map[string]Tile{}
+	for x, tileType := range []string{"stone", "platform", "spikes", "decor"} {
+		tile := Tile{Position: types.Vector{X: float64(x)}, Type: tileType}
+		tiles[tileKey(tile)] = tile
+	}
+	data, err := json.Marshal(tileMapJSON{TileSize: 16, Tiles: tiles})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tileMap := &TileMapType{}
+	if err := json.Unmarshal(data, tileMap); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tile := range tileMap.Layer("solid").allTiles() {
+		if tile.Type == "decor" {
+			t.Errorf("decor is on the solid layer")
+		}
+	}
+	if got := len(tileMap.Layer("solid").allTiles()); got != 3 {
+		t.Errorf("%d tiles on the solid layer, want 3", got)
+	}
+}
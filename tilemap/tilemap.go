@@ -2,27 +2,28 @@ package tilemap
 
 import (
 	"encoding/json"
+	"io/fs"
+	"math"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/yuricorredor/platformer/assets"
 	"github.com/yuricorredor/platformer/rects"
 	"github.com/yuricorredor/platformer/types"
 )
 
 var (
 	NeighboursOffset = []types.Vector{
-		{X: -1, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: -1},
-		{X: 1, Y: -1}, {X: 1, Y: 0}, {X: 1, Y: 1},
-		{X: 0, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: 1},
-		{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1},
+		{X: -1, Y: -1}, {X: 0, Y: -1}, {X: 1, Y: -1},
+		{X: -1, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0},
+		{X: -1, Y: 1}, {X: 0, Y: 1}, {X: 1, Y: 1},
 	}
-	PhysicsTiles = []string{"grass", "stone"}
 )
 
 var TileMap = &TileMapType{
 	TileSize: 16,
+	Layers:   DefaultLayers(),
 }
 
 type Vector = types.Vector
@@ -33,65 +34,86 @@ type Tile struct {
 	Type     string
 }
 
+// Layers are kept sorted by Z. Music and Ambience name the tracks played
+// while the map is on, see audio.Music. A Playlist, when set, is played
+// instead of Music.
 type TileMapType struct {
-	TileSize     int
-	Tiles        map[string]Tile
-	OffGridTiles []Tile
+	TileSize int
+	Layers   []*Layer
+	Music    string   `json:",omitempty"`
+	Playlist []string `json:",omitempty"`
+	Ambience string   `json:",omitempty"`
 }
 
 func (t *TileMapType) Update() error {
 	return nil
 }
 
+// Draw draws every layer, as the editor does.
 func (t *TileMapType) Draw(screen *ebiten.Image, scrollX, scrollY int, renderContext string) {
-	for _, tile := range t.OffGridTiles {
-		options := &ebiten.DrawImageOptions{}
-		options.GeoM.Translate(tile.Position.X*float64(t.TileSize)-float64(scrollX), tile.Position.Y*float64(t.TileSize)-float64(scrollY))
-
-		var shouldRender bool
-		if renderContext == "game" {
-			shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnGame
-		} else if renderContext == "editor" {
-			shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnEditor
-		}
+	for _, layer := range t.Layers {
+		layer.Draw(screen, t.TileSize, scrollX, scrollY, renderContext)
+	}
+}
 
-		if shouldRender {
-			screen.DrawImage(assets.Assets.Images[tile.Type].Image[tile.Variant], options)
-		}
+// DrawBackground draws the layers that go under the entities.
+func (t *TileMapType) DrawBackground(screen *ebiten.Image, scrollX, scrollY int, renderContext string) {
+	for _, layer := range t.layersInFront(false) {
+		layer.Draw(screen, t.TileSize, scrollX, scrollY, renderContext)
 	}
+}
 
-	screenWidth := screen.Bounds().Max.X
-	screenHeight := screen.Bounds().Max.Y
+// DrawForeground draws the layers that go over the entities.
+func (t *TileMapType) DrawForeground(screen *ebiten.Image, scrollX, scrollY int, renderContext string) {
+	for _, layer := range t.layersInFront(true) {
+		layer.Draw(screen, t.TileSize, scrollX, scrollY, renderContext)
+	}
+}
 
-	for x := scrollX / t.TileSize; x < (scrollX+screenWidth)/t.TileSize+1; x++ {
-		for y := scrollY / t.TileSize; y < (scrollY+screenHeight)/t.TileSize+1; y++ {
-			location := strconv.Itoa(x) + ";" + strconv.Itoa(y)
-			if tile, ok := t.Tiles[location]; ok {
-				options := &ebiten.DrawImageOptions{}
-				options.GeoM.Translate(tile.Position.X*float64(t.TileSize)-float64(scrollX), tile.Position.Y*float64(t.TileSize)-float64(scrollY))
+// layersInFront returns the layers above ForegroundZ, or the others, in
+// drawing order.
+func (t *TileMapType) layersInFront(front bool) []*Layer {
+	layers := []*Layer{}
+	for _, layer := range t.Layers {
+		if (layer.Z > ForegroundZ) == front {
+			layers = append(layers, layer)
+		}
+	}
 
-				var shouldRender bool
-				if renderContext == "game" {
-					shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnGame
-				} else if renderContext == "editor" {
-					shouldRender = assets.Assets.Images[tile.Type].ShouldRenderOnEditor
-				}
+	return layers
+}
 
-				if shouldRender {
-					screen.DrawImage(assets.Assets.Images[tile.Type].Image[tile.Variant], options)
-				}
-			}
+// Layer returns the layer with the given name, or nil.
+func (t *TileMapType) Layer(name string) *Layer {
+	for _, layer := range t.Layers {
+		if layer.Name == name {
+			return layer
 		}
 	}
+
+	return nil
+}
+
+// AddLayer adds a layer, keeping the layers in drawing order.
+func (t *TileMapType) AddLayer(layer *Layer) {
+	t.Layers = append(t.Layers, layer)
+	t.sortLayers()
+}
+
+func (t *TileMapType) sortLayers() {
+	sort.SliceStable(t.Layers, func(i, j int) bool {
+		return t.Layers[i].Z < t.Layers[j].Z
+	})
 }
 
 func (t *TileMapType) TilesAroundPosition(position types.Vector) []Tile {
 	tiles := []Tile{}
-	tileLoc := types.Vector{X: position.X / float64(t.TileSize), Y: position.Y / float64(t.TileSize)}
-	for _, offset := range NeighboursOffset {
-		checkLocation := strconv.Itoa(int(tileLoc.X+offset.X)) + ";" + strconv.Itoa(int(tileLoc.Y+offset.Y))
-		if tile, ok := t.Tiles[checkLocation]; ok {
-			tiles = append(tiles, tile)
+	tileX, tileY := t.tileLocation(position)
+	for _, layer := range t.Layers {
+		for _, offset := range NeighboursOffset {
+			if tile, ok := layer.TileAt(tileX+int(offset.X), tileY+int(offset.Y)); ok {
+				tiles = append(tiles, tile)
+			}
 		}
 	}
 
@@ -99,61 +121,124 @@ func (t *TileMapType) TilesAroundPosition(position types.Vector) []Tile {
 }
 
 func (t *TileMapType) PhysicsRectsAroundPosition(position types.Vector) []rects.Rect {
-	rectsList := []rects.Rect{}
-	for _, tile := range t.TilesAroundPosition(position) {
-		for _, physicsTile := range PhysicsTiles {
-			if tile.Type == physicsTile {
-				rect := rects.Rect{
-					X:      tile.Position.X * float64(t.TileSize),
-					Y:      tile.Position.Y * float64(t.TileSize),
-					Width:  float64(t.TileSize),
-					Height: float64(t.TileSize),
-				}
+	tileX, tileY := t.tileLocation(position)
+	return solidRects(t.physicsTiles(tileX-1, tileY-1, tileX+1, tileY+1))
+}
 
-				rectsList = append(rectsList, rect)
-			}
+// PhysicsRectsInRect returns the solid tiles overlapping area, however large
+// it is, unlike PhysicsRectsAroundPosition which only looks one tile around.
+func (t *TileMapType) PhysicsRectsInRect(area rects.Rect) []rects.Rect {
+	return solidRects(t.PhysicsTilesInRect(area))
+}
+
+// PhysicsTile is a tile of a colliding layer, with its properties from
+// TileTypes.
+type PhysicsTile struct {
+	Rect       rects.Rect
+	Properties TileProperties
+}
+
+// PhysicsTilesInRect returns every tile of the colliding layers overlapping
+// area, solid or not, so that one-way platforms and hazards can be handled.
+func (t *TileMapType) PhysicsTilesInRect(area rects.Rect) []PhysicsTile {
+	left, top := t.tileLocation(types.Vector{X: area.Left(), Y: area.Top()})
+	right, bottom := t.tileLocation(types.Vector{X: area.Right(), Y: area.Bottom()})
+	return t.physicsTiles(left, top, right, bottom)
+}
+
+func (t *TileMapType) physicsTiles(left, top, right, bottom int) []PhysicsTile {
+	tiles := []PhysicsTile{}
+	for _, layer := range t.Layers {
+		if !layer.Collides {
+			continue
+		}
+
+		layer.TilesInRange(left, top, right, bottom, func(tile Tile) {
+			tiles = append(tiles, PhysicsTile{Rect: t.tileRect(tile), Properties: TileTypes.Properties(tile)})
+		})
+	}
+
+	return tiles
+}
+
+func solidRects(tiles []PhysicsTile) []rects.Rect {
+	rectsList := []rects.Rect{}
+	for _, tile := range tiles {
+		if tile.Properties.Solid {
+			rectsList = append(rectsList, tile.Rect)
 		}
 	}
 
 	return rectsList
 }
 
+func (t *TileMapType) tileLocation(position types.Vector) (int, int) {
+	return int(math.Floor(position.X / float64(t.TileSize))), int(math.Floor(position.Y / float64(t.TileSize)))
+}
+
+func (t *TileMapType) tileRect(tile Tile) rects.Rect {
+	return rects.Rect{
+		X:      tile.Position.X * float64(t.TileSize),
+		Y:      tile.Position.Y * float64(t.TileSize),
+		Width:  float64(t.TileSize),
+		Height: float64(t.TileSize),
+	}
+}
+
 func (t *TileMapType) CheckForSolid(position types.Vector) bool {
-	tileLoc := types.Vector{X: position.X / float64(t.TileSize), Y: position.Y / float64(t.TileSize)}
-	location := strconv.Itoa(int(tileLoc.X)) + ";" + strconv.Itoa(int(tileLoc.Y))
-	if tile, ok := t.Tiles[location]; ok {
-		for _, physicsTile := range PhysicsTiles {
-			if tile.Type == physicsTile {
-				return true
-			}
+	return t.checkTile(position, func(properties TileProperties) bool {
+		return properties.Solid
+	})
+}
+
+// CheckForFloor is like CheckForSolid but also counts one-way platforms,
+// which can be walked on.
+func (t *TileMapType) CheckForFloor(position types.Vector) bool {
+	return t.checkTile(position, func(properties TileProperties) bool {
+		return properties.Solid || properties.OneWay
+	})
+}
+
+func (t *TileMapType) checkTile(position types.Vector, match func(TileProperties) bool) bool {
+	tileX, tileY := t.tileLocation(position)
+	for _, layer := range t.Layers {
+		if !layer.Collides {
+			continue
+		}
+		if tile, ok := layer.TileAt(tileX, tileY); ok && match(TileTypes.Properties(tile)) {
+			return true
 		}
 	}
 
 	return false
 }
 
+// Extract finds the tiles matching pairs on every layer. Grid tiles are
+// returned with their position in pixels, like off-grid ones.
 func (t *TileMapType) Extract(pairs []types.Pair, keep bool) []Tile {
 	matches := []Tile{}
 
 	for _, pair := range pairs {
-		for _, tile := range t.OffGridTiles {
-			if tile.Type == pair.AssetType && tile.Variant == pair.AssetVariant {
-				matches = append(matches, tile)
-				if !keep {
-					t.RemoveOffGridTile(tile)
+		for _, layer := range t.Layers {
+			for _, tile := range layer.OffGridTiles {
+				if tile.Type == pair.AssetType && tile.Variant == pair.AssetVariant {
+					matches = append(matches, tile)
+					if !keep {
+						layer.RemoveOffGridTile(tile)
+					}
 				}
 			}
-		}
 
-		for _, tile := range t.Tiles {
-			if tile.Type == pair.AssetType && tile.Variant == pair.AssetVariant {
-				toAppend := tile
-				toAppend.Position.X *= float64(t.TileSize)
-				toAppend.Position.Y *= float64(t.TileSize)
-				matches = append(matches, toAppend)
+			for _, tile := range sortTiles(layer.allTiles()) {
+				if tile.Type == pair.AssetType && tile.Variant == pair.AssetVariant {
+					toAppend := tile
+					toAppend.Position.X *= float64(t.TileSize)
+					toAppend.Position.Y *= float64(t.TileSize)
+					matches = append(matches, toAppend)
 
-				if !keep {
-					t.RemoveTile(types.Vector{X: tile.Position.X, Y: tile.Position.Y})
+					if !keep {
+						layer.RemoveTile(types.Vector{X: tile.Position.X, Y: tile.Position.Y})
+					}
 				}
 			}
 		}
@@ -162,25 +247,79 @@ func (t *TileMapType) Extract(pairs []types.Pair, keep bool) []Tile {
 	return matches
 }
 
-func (t *TileMapType) SetTile(tile Tile) {
-	t.Tiles[strconv.Itoa(int(tile.Position.X))+";"+strconv.Itoa(int(tile.Position.Y))] = tile
+// sortTiles orders grid tiles row by row, so callers that consume randomness
+// per tile behave the same on every run.
+func sortTiles(tiles []Tile) []Tile {
+	sort.Slice(tiles, func(i, j int) bool {
+		if tiles[i].Position.Y != tiles[j].Position.Y {
+			return tiles[i].Position.Y < tiles[j].Position.Y
+		}
+		return tiles[i].Position.X < tiles[j].Position.X
+	})
+
+	return tiles
 }
 
-func (t *TileMapType) RemoveTile(position types.Vector) {
-	delete(t.Tiles, strconv.Itoa(int(position.X))+";"+strconv.Itoa(int(position.Y)))
+// TileCount returns how many grid tiles the map holds, on all layers.
+func (t *TileMapType) TileCount() int {
+	count := 0
+	for _, layer := range t.Layers {
+		count += layer.TileCount()
+	}
+
+	return count
 }
 
-func (t *TileMapType) SetOffGridTile(tile Tile) {
-	t.OffGridTiles = append(t.OffGridTiles, tile)
+// tileMapJSON is the format maps are saved in. Tiles and OffGridTiles are
+// only read, from maps saved before layers existed.
+type tileMapJSON struct {
+	TileSize     int
+	Layers       []*Layer        `json:",omitempty"`
+	Tiles        map[string]Tile `json:",omitempty"`
+	OffGridTiles []Tile          `json:",omitempty"`
+	Music        string          `json:",omitempty"`
+	Playlist     []string        `json:",omitempty"`
+	Ambience     string          `json:",omitempty"`
 }
 
-func (t *TileMapType) RemoveOffGridTile(tile Tile) {
-	for i, offGridTile := range t.OffGridTiles {
-		if offGridTile.Position == tile.Position {
-			t.OffGridTiles = append(t.OffGridTiles[:i], t.OffGridTiles[i+1:]...)
-			break
+func (t *TileMapType) UnmarshalJSON(data []byte) error {
+	var saved tileMapJSON
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	*t = TileMapType{
+		TileSize: saved.TileSize,
+		Layers:   saved.Layers,
+		Music:    saved.Music,
+		Playlist: saved.Playlist,
+		Ambience: saved.Ambience,
+	}
+	if len(t.Layers) == 0 {
+		t.Layers = legacyLayers(saved.Tiles, saved.OffGridTiles)
+	}
+	t.sortLayers()
+
+	return nil
+}
+
+// legacyLayers splits the single grid of an old map into the default layers,
+// tiles with an entry in TileTypes going to the solid one, so one-way
+// platforms and hazards keep colliding.
+func legacyLayers(tiles map[string]Tile, offGridTiles []Tile) []*Layer {
+	layers := DefaultLayers()
+	background, solid := layers[0], layers[1]
+
+	for _, tile := range tiles {
+		if _, ok := TileTypes.Lookup(tile); ok {
+			solid.SetTile(tile)
+		} else {
+			background.SetTile(tile)
 		}
 	}
+	background.OffGridTiles = offGridTiles
+
+	return layers
 }
 
 func (t *TileMapType) ToJSONString() string {
@@ -193,35 +332,22 @@ func (t *TileMapType) ToJSONString() string {
 }
 
 func (t *TileMapType) Save(path string) error {
-	// Create file if not exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		os.Create(path)
-	}
-
-	// Open file
-	f, err := os.OpenFile(path, os.O_RDWR, 0755)
-	if err != nil {
-		panic(err)
-	}
-
-	// Write to file
-	_, err = f.WriteString(t.ToJSONString())
-	if err != nil {
-		return err
-	}
-
-	// Close file
-	err = f.Close()
+	data, err := json.MarshalIndent(t, "", "")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.WriteFile(path, data, 0644)
 }
 
 func (t *TileMapType) Load(path string) error {
+	return t.LoadFS(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// LoadFS is like Load but reads the map from fsys, such as assets.FS.
+func (t *TileMapType) LoadFS(fsys fs.FS, path string) error {
 	// Open file
-	f, err := os.OpenFile(path, os.O_RDWR, 0755)
+	f, err := fsys.Open(path)
 	if err != nil {
 		return err
 	}
@@ -240,7 +366,7 @@ func (t *TileMapType) Load(path string) error {
 	}
 
 	// Set tilemap
-	TileMap = &tileMap
+	*t = tileMap
 
 	return nil
 }
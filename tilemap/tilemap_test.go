@@ -0,0 +1,38 @@
+package tilemap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yuricorredor/platformer/types"
+)
+
+func TestSaveReportsErrors(t *testing.T) {
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+
+	if err := tileMap.Save(filepath.Join(t.TempDir(), "missing", "map.json")); err == nil {
+		t.Error("saving into a missing directory did not fail")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers(), Music: "meadow"}
+	tileMap.Layer("solid").SetTile(Tile{Position: types.Vector{X: -3, Y: 7}, Type: "stone", Variant: 2})
+	path := filepath.Join(t.TempDir(), "map.json")
+
+	if err := tileMap.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &TileMapType{}
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Music != "meadow" {
+		t.Errorf("music = %q, want meadow", loaded.Music)
+	}
+	tile, ok := loaded.Layer("solid").TileAt(-3, 7)
+	if !ok || tile.Type != "stone" || tile.Variant != 2 {
+		t.Errorf("tile = %+v, %v, want stone 2", tile, ok)
+	}
+}
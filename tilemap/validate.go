@@ -0,0 +1,57 @@
+package tilemap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuricorredor/platformer/assets"
+)
+
+// ValidationError lists every tile of a map that can't be drawn with the
+// loaded assets.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d invalid tiles:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Validate checks the Type and Variant of every tile against
+// assets.Assets. It returns a *ValidationError when some tile is off.
+func (t *TileMapType) Validate() error {
+	problems := []string{}
+
+	for _, layer := range t.Layers {
+		for _, tile := range sortTiles(layer.allTiles()) {
+			if problem := validateTile(tile); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: tile %v;%v: %s", layer.Name, tile.Position.X, tile.Position.Y, problem))
+			}
+		}
+
+		for _, tile := range layer.OffGridTiles {
+			if problem := validateTile(tile); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: off-grid tile %v;%v: %s", layer.Name, tile.Position.X, tile.Position.Y, problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+func validateTile(tile Tile) string {
+	asset, ok := assets.Assets.Images[tile.Type]
+	if !ok {
+		return fmt.Sprintf("unknown type %q", tile.Type)
+	}
+
+	if tile.Variant < 0 || tile.Variant >= len(asset.Image) {
+		return fmt.Sprintf("%s has no variant %d, only 0 to %d", tile.Type, tile.Variant, len(asset.Image)-1)
+	}
+
+	return ""
+}
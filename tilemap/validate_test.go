@@ -0,0 +1,45 @@
+package tilemap
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/types"
+)
+
+func TestValidate(t *testing.T) {
+	if err := assets.Load(assets.FS); err != nil {
+		t.Fatal(err)
+	}
+
+	tileMap := &TileMapType{TileSize: 16, Layers: DefaultLayers()}
+	if err := tileMap.Validate(); err != nil {
+		t.Fatalf("empty map: %v", err)
+	}
+
+	solid := tileMap.Layer("solid")
+	solid.SetTile(Tile{Position: types.Vector{X: 0, Y: 0}, Type: "grass", Variant: 8})
+	solid.SetTile(Tile{Position: types.Vector{X: 1, Y: 2}, Type: "lava"})
+	solid.SetTile(Tile{Position: types.Vector{X: 3, Y: 4}, Type: "grass", Variant: 99})
+	tileMap.Layer("background").SetOffGridTile(Tile{Position: types.Vector{X: 1.5, Y: -2}, Type: "decor", Variant: -1})
+
+	err := tileMap.Validate()
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("error = %v, want a *ValidationError", err)
+	}
+
+	grass := len(assets.Assets.Images["grass"].Image)
+	decor := len(assets.Assets.Images["decor"].Image)
+	want := []string{
+		`background: off-grid tile 1.5;-2: decor has no variant -1, only 0 to ` + strconv.Itoa(decor-1),
+		`solid: tile 1;2: unknown type "lava"`,
+		`solid: tile 3;4: grass has no variant 99, only 0 to ` + strconv.Itoa(grass-1),
+	}
+	if !reflect.DeepEqual(validationError.Problems, want) {
+		t.Errorf("problems = %q, want %q", validationError.Problems, want)
+	}
+}
@@ -0,0 +1,166 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/yuricorredor/platformer/entities"
+	"github.com/yuricorredor/platformer/tilemap"
+	"github.com/yuricorredor/platformer/types"
+)
+
+// settledWorld is a test world whose player stands still on the floor, on a
+// frame where it touched it.
+func settledWorld(t *testing.T) *World {
+	t.Helper()
+
+	w := newTestWorld(t)
+	step(w, entities.Input{}, 60)
+	for w.Player.AirTime != 0 {
+		w.Step(entities.Input{})
+	}
+
+	return w
+}
+
+func removeFloor(w *World) {
+	for x := 0; x < 30; x++ {
+		w.TileMap.Layer("solid").RemoveTile(types.Vector{X: float64(x), Y: floorY})
+	}
+}
+
+func TestCoyoteTime(t *testing.T) {
+	coyote := entities.DefaultPlayerConfig().CoyoteFrames
+
+	// The floor vanishes, the player falls for some frames then presses jump.
+	// AirTime only counts the frames after the first one in the air.
+	for _, test := range []struct {
+		fallFrames int
+		jumps      bool
+	}{
+		{fallFrames: 0, jumps: true},
+		{fallFrames: coyote, jumps: true},
+		{fallFrames: coyote + 1, jumps: false},
+	} {
+		w := settledWorld(t)
+		removeFloor(w)
+
+		step(w, entities.Input{}, test.fallFrames)
+		w.Step(entities.Input{Jump: true})
+
+		if jumped := w.Player.Velocity.Y < 0; jumped != test.jumps {
+			t.Errorf("after falling %d frames: jumped = %v, want %v", test.fallFrames, jumped, test.jumps)
+		}
+	}
+}
+
+// dropPlayer lifts the player well above the floor, past its coyote time.
+func dropPlayer(w *World) {
+	w.Player.Position.Y -= 80
+	w.Player.Velocity = types.Vector{}
+	w.Player.AirTime = w.Player.Config.CoyoteFrames + 1
+}
+
+func TestJumpBuffer(t *testing.T) {
+	buffer := entities.DefaultPlayerConfig().JumpBufferFrames
+
+	// Find the frame the player lands on, the first jump attempt that can
+	// succeed is on the frame after it.
+	dry := settledWorld(t)
+	dropPlayer(dry)
+	landing := 0
+	for landing = 1; landing < 200; landing++ {
+		dry.Step(entities.Input{})
+		if dry.Player.AirTime == 0 {
+			break
+		}
+	}
+
+	for _, test := range []struct {
+		early int
+		jumps bool
+	}{
+		{early: 0, jumps: true},
+		{early: buffer, jumps: true},
+		{early: buffer + 1, jumps: false},
+	} {
+		w := settledWorld(t)
+		dropPlayer(w)
+
+		press := landing + 1 - test.early
+		for frame := 1; frame <= landing+1; frame++ {
+			w.Step(entities.Input{Jump: frame == press})
+		}
+
+		if jumped := w.Player.Velocity.Y < 0; jumped != test.jumps {
+			t.Errorf("jump pressed %d frames early: jumped = %v, want %v", test.early, jumped, test.jumps)
+		}
+	}
+}
+
+func TestJumpCut(t *testing.T) {
+	apex := func(release int) float64 {
+		w := settledWorld(t)
+		start := w.Player.Position.Y
+
+		w.Step(entities.Input{Jump: true})
+		for frame := 2; w.Player.Velocity.Y < 0; frame++ {
+			w.Step(entities.Input{HoldJump: frame < release})
+		}
+
+		return start - w.Player.Position.Y
+	}
+
+	held, cut := apex(1000), apex(3)
+	if cut >= held {
+		t.Errorf("releasing jump while rising reached %v pixels up, holding it %v", cut, held)
+	}
+
+	// Releasing after the top of the jump changes nothing.
+	w := settledWorld(t)
+	w.Step(entities.Input{Jump: true})
+	for w.Player.Velocity.Y < 0 {
+		w.Step(entities.Input{HoldJump: true})
+	}
+	falling := w.Player.Velocity.Y
+	w.Step(entities.Input{})
+	if want := falling + w.Player.Config.Gravity; w.Player.Velocity.Y != want {
+		t.Errorf("velocity after a release while falling = %v, want %v", w.Player.Velocity.Y, want)
+	}
+}
+
+func TestWallCoyote(t *testing.T) {
+	coyote := entities.DefaultPlayerConfig().CoyoteFrames
+	const wallX = 8
+
+	// The player slides down a wall on its right, lets go, and after some
+	// frames presses jump and right for a wall jump.
+	for _, test := range []struct {
+		freeFrames int
+		jumps      bool
+	}{
+		{freeFrames: 1, jumps: true},
+		{freeFrames: coyote, jumps: true},
+		{freeFrames: coyote + 1, jumps: false},
+	} {
+		w := settledWorld(t)
+		for y := 0; y < floorY; y++ {
+			w.TileMap.Layer("solid").SetTile(tilemap.Tile{Position: types.Vector{X: wallX, Y: float64(y)}, Type: "stone"})
+		}
+		w.Player.Position = types.Vector{X: wallX*16 - w.Player.Width - 1, Y: 2 * 16}
+		w.Player.Velocity = types.Vector{}
+
+		for frame := 0; frame < 60 && !w.Player.WallSlide; frame++ {
+			w.Step(entities.Input{Right: true})
+		}
+		if !w.Player.WallSlide {
+			t.Fatal("player is not sliding down the wall")
+		}
+
+		step(w, entities.Input{}, test.freeFrames)
+		w.Step(entities.Input{Jump: true, Right: true})
+
+		if jumped := w.Player.Velocity.X < 0; jumped != test.jumps {
+			t.Errorf("after %d frames off the wall: wall jumped = %v, want %v", test.freeFrames, jumped, test.jumps)
+		}
+	}
+}
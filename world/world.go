@@ -0,0 +1,434 @@
+package world
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/audio"
+	"github.com/yuricorredor/platformer/clouds"
+	"github.com/yuricorredor/platformer/entities"
+	"github.com/yuricorredor/platformer/particle"
+	"github.com/yuricorredor/platformer/rects"
+	"github.com/yuricorredor/platformer/tilemap"
+	"github.com/yuricorredor/platformer/types"
+)
+
+const (
+	SpawnerPlayer = iota
+	SpawnerEnemy
+	SpawnerCheckpoint
+)
+
+// RespawnFrames is how long the level keeps running after the player dies,
+// ClearFrames how long it keeps running after the last enemy is killed.
+const (
+	RespawnFrames = 60
+	ClearFrames   = 60
+	HitStopFrames = 6
+	ShakeFrames   = 16
+	DuckFrames    = 30
+)
+
+// World owns the whole simulation of one level. It never touches the window
+// or the keyboard, so any number of worlds can be stepped side by side.
+type World struct {
+	entities.Scene
+	Enemies     []*entities.EnemyEntity
+	Clouds      *clouds.CloudsType
+	Leafs       *particle.Leafs
+	Spawners    []tilemap.Tile
+	Checkpoint  types.Vector
+	RespawnTime int
+	ClearTime   int
+	HitStop     int
+	ScreenShake int
+	Seed        int64
+	Frame       int
+	// latched holds the jump and dash presses made during hit-stop, so they
+	// reach the player on the first frame after it.
+	latched entities.Input
+}
+
+type EntitySnapshot struct {
+	Position types.Vector
+	Velocity types.Vector
+	Action   string
+	Flipped  bool
+}
+
+type Snapshot struct {
+	Frame       int
+	Health      int
+	Dead        bool
+	Player      EntitySnapshot
+	Enemies     []EntitySnapshot
+	Projectiles []types.Vector
+}
+
+func New(fsys fs.FS, path string, seed int64, playerConfig *entities.PlayerConfig) (*World, error) {
+	tileMap := &tilemap.TileMapType{}
+	if err := tileMap.LoadFS(fsys, path); err != nil {
+		return nil, err
+	}
+	if err := tileMap.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	w := &World{
+		Scene: entities.Scene{
+			Rand:          rand.New(rand.NewSource(seed)),
+			TileMap:       tileMap,
+			Player:        entities.CreatePlayer(types.Vector{X: 0, Y: 0}, playerConfig),
+			DashParticles: &particle.DashParticlesType{},
+			Projectiles:   &particle.ProjectilesType{},
+			Sparks:        &particle.SparksType{},
+			Audio:         audio.Null{},
+		},
+		Enemies: []*entities.EnemyEntity{},
+		Seed:    seed,
+		Clouds: &clouds.CloudsType{
+			CloudImages: assets.Assets.Images["clouds"].Image,
+			Count:       16,
+		},
+	}
+
+	w.Clouds.GenerateRandomClouds(w.Rand)
+	w.setTileMap(tileMap)
+
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerPlayer {
+			w.Checkpoint = spawner.Position
+		}
+	}
+
+	w.spawn()
+
+	return w, nil
+}
+
+// ReloadMap swaps the tiles of the level for the ones in path. The player
+// stays where it is unless it would end up inside a wall, and enemies are
+// spawned again from the new spawners.
+func (w *World) ReloadMap(fsys fs.FS, path string) error {
+	tileMap := &tilemap.TileMapType{}
+	if err := tileMap.LoadFS(fsys, path); err != nil {
+		return err
+	}
+	if err := tileMap.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	w.setTileMap(tileMap)
+
+	checkpointFound := false
+	for _, spawner := range w.Spawners {
+		if spawner.Position == w.Checkpoint && (spawner.Variant == SpawnerPlayer || spawner.Variant == SpawnerCheckpoint) {
+			checkpointFound = true
+		}
+	}
+	if !checkpointFound {
+		for _, spawner := range w.Spawners {
+			if spawner.Variant == SpawnerPlayer {
+				w.Checkpoint = spawner.Position
+			}
+		}
+	}
+
+	if len(tileMap.PhysicsRectsInRect(w.Player.Rect())) > 0 {
+		w.Player.Position = w.Checkpoint
+		w.Player.Velocity = types.Vector{X: 0, Y: 0}
+	}
+
+	w.Enemies = []*entities.EnemyEntity{}
+	w.Projectiles.Particles = nil
+	w.ClearTime = 0
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerEnemy {
+			w.Enemies = append(w.Enemies, entities.CreateEnemy(spawner.Position))
+		}
+	}
+
+	return nil
+}
+
+// RequiredImages are the image groups the world and its entities index
+// directly, with the number of frames each needs at least, and
+// RequiredSheetTags the clips of the player sheet its states play.
+var (
+	RequiredImages = map[string]int{
+		"background":    1,
+		"clouds":        1,
+		"spawners":      SpawnerCheckpoint + 1,
+		"player":        1,
+		"enemy":         1,
+		"enemy_idle":    1,
+		"enemy_run":     1,
+		"gun":           1,
+		"particle":      1,
+		"particle_leaf": 2,
+		"projectile":    1,
+	}
+	RequiredSheetTags = map[string][]string{
+		"player": {"idle", "run", "jump", "slide", "wall_slide"},
+	}
+)
+
+// CheckAssets reports what a level would be missing from assets.Assets, so a
+// bad reload can be refused before anything indexes it.
+func CheckAssets() error {
+	return assets.Assets.Require(RequiredImages, RequiredSheetTags)
+}
+
+// RefreshAssets points everything of the level that holds images at the
+// current assets.Assets, once it has been reloaded.
+func (w *World) RefreshAssets() {
+	w.Player.Animator.Clips = entities.PlayerClips()
+	w.Player.Animator.Reset()
+
+	for _, enemy := range w.Enemies {
+		enemy.Animator.Clips = entities.EnemyClips()
+		enemy.Animator.Reset()
+	}
+
+	w.Clouds.SetImages(assets.Assets.Images["clouds"].Image)
+}
+
+func (w *World) setTileMap(tileMap *tilemap.TileMapType) {
+	w.TileMap = tileMap
+	w.Leafs = particle.CreateLeafs(tileMap)
+
+	w.Spawners = tileMap.Extract([]types.Pair{
+		{
+			AssetType:    "spawners",
+			AssetVariant: SpawnerPlayer,
+		},
+		{
+			AssetType:    "spawners",
+			AssetVariant: SpawnerEnemy,
+		},
+		{
+			AssetType:    "spawners",
+			AssetVariant: SpawnerCheckpoint,
+		},
+	}, true)
+}
+
+// spawn puts the level back in its initial state: every enemy returns to its
+// spawner and the player starts over at the last checkpoint reached.
+func (w *World) spawn() {
+	w.Player = entities.CreatePlayer(w.Checkpoint, w.Player.Config)
+	w.Enemies = []*entities.EnemyEntity{}
+	w.Projectiles.Particles = nil
+	w.RespawnTime = 0
+	w.ClearTime = 0
+
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerEnemy {
+			w.Enemies = append(w.Enemies, entities.CreateEnemy(spawner.Position))
+		}
+	}
+}
+
+func (w *World) checkpointRect(checkpoint tilemap.Tile) rects.Rect {
+	return rects.Rect{
+		X:      checkpoint.Position.X,
+		Y:      checkpoint.Position.Y,
+		Width:  float64(w.TileMap.TileSize),
+		Height: float64(w.TileMap.TileSize),
+	}
+}
+
+func (w *World) updateCheckpoints() {
+	playerRect := w.Player.Rect()
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerCheckpoint && playerRect.Colliderect(w.checkpointRect(spawner)) {
+			w.Checkpoint = spawner.Position
+		}
+	}
+}
+
+func (w *World) Step(input entities.InputSource) {
+	w.Frame++
+
+	if w.ScreenShake > 0 {
+		w.ScreenShake--
+	}
+	if w.HitStop > 0 {
+		w.HitStop--
+		w.latched.Jump = w.latched.Jump || input.JumpPressed()
+		w.latched.Dash = w.latched.Dash || input.DashPressed()
+		return
+	}
+	if w.latched.Jump || w.latched.Dash {
+		input = entities.Input{
+			Left:     input.MoveLeft(),
+			Right:    input.MoveRight(),
+			Jump:     w.latched.Jump || input.JumpPressed(),
+			Dash:     w.latched.Dash || input.DashPressed(),
+			HoldJump: input.JumpHeld(),
+		}
+		w.latched = entities.Input{}
+	}
+
+	w.Clouds.Update()
+	w.Player.Update(input, &w.Scene)
+
+	for _, enemy := range w.Enemies {
+		enemy.Update(&w.Scene)
+	}
+
+	w.updateDashAttack()
+
+	w.DashParticles.Update()
+	hits := w.Projectiles.Update(w.TileMap, w.Player.IsDashing() || w.Player.Dead, w.Player.Rect())
+	w.Sparks.Update()
+	w.Leafs.Update(w.Rand)
+
+	// Hazard tiles such as spikes hurt like a projectile would.
+	if w.Player.Damage(hits+w.Player.Hazard, &w.Scene) {
+		w.RespawnTime = RespawnFrames
+		w.Audio.Duck(DuckFrames)
+	}
+
+	if w.Player.Dead {
+		w.RespawnTime--
+		if w.RespawnTime <= 0 {
+			w.spawn()
+		}
+	} else {
+		w.updateCheckpoints()
+	}
+
+	if w.Cleared() {
+		w.ClearTime++
+	}
+}
+
+// Cleared reports whether every enemy of the level has been killed.
+func (w *World) Cleared() bool {
+	return len(w.Enemies) == 0 && w.enemySpawners() > 0
+}
+
+// Finished reports whether the level was cleared long enough ago to move on
+// to the next one.
+func (w *World) Finished() bool {
+	return w.ClearTime >= ClearFrames
+}
+
+func (w *World) enemySpawners() int {
+	count := 0
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerEnemy {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (w *World) updateDashAttack() {
+	if !w.Player.IsDashing() || w.Player.Dead {
+		return
+	}
+
+	playerRect := w.Player.Rect()
+	remainingEnemies := []*entities.EnemyEntity{}
+	for _, enemy := range w.Enemies {
+		if playerRect.Colliderect(enemy.Rect()) {
+			w.killEnemy(enemy)
+			continue
+		}
+
+		remainingEnemies = append(remainingEnemies, enemy)
+	}
+
+	w.Enemies = remainingEnemies
+}
+
+func (w *World) killEnemy(enemy *entities.EnemyEntity) {
+	rect := enemy.Rect()
+	position := types.Vector{X: rect.CenterX(), Y: rect.CenterY()}
+
+	for i := 0; i < 30; i++ {
+		angle := w.Rand.Float64() * math.Pi * 2
+		speed := w.Rand.Float64() * 5
+		w.Sparks.Particles = append(w.Sparks.Particles, particle.NewSpark(angle, position, types.Vector{X: speed + 2, Y: speed + 2}))
+
+		velocity := types.Vector{
+			X: math.Cos(angle+math.Pi) * speed * 0.5,
+			Y: math.Sin(angle+math.Pi) * speed * 0.5,
+		}
+		w.DashParticles.Particles = append(w.DashParticles.Particles, particle.CreateDashParticle(w.Rand, velocity, position))
+	}
+
+	w.Sparks.Particles = append(w.Sparks.Particles,
+		particle.NewSpark(0, position, types.Vector{X: 5 + w.Rand.Float64(), Y: 5 + w.Rand.Float64()}),
+		particle.NewSpark(math.Pi, position, types.Vector{X: 5 + w.Rand.Float64(), Y: 5 + w.Rand.Float64()}),
+	)
+
+	w.Audio.PlayAt("hit", position)
+	w.Audio.Duck(DuckFrames)
+	w.HitStop = HitStopFrames
+	w.ScreenShake = int(math.Max(float64(w.ScreenShake), ShakeFrames))
+}
+
+func (w *World) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Frame:       w.Frame,
+		Health:      w.Player.Health,
+		Dead:        w.Player.Dead,
+		Player:      entitySnapshot(w.Player.Position, w.Player.Velocity, w.Player.Action, w.Player.Flipped),
+		Enemies:     make([]EntitySnapshot, 0, len(w.Enemies)),
+		Projectiles: make([]types.Vector, 0, len(w.Projectiles.Particles)),
+	}
+
+	for _, enemy := range w.Enemies {
+		snapshot.Enemies = append(snapshot.Enemies, entitySnapshot(enemy.Position, enemy.Velocity, enemy.Action, enemy.Flipped))
+	}
+	for _, projectile := range w.Projectiles.Particles {
+		snapshot.Projectiles = append(snapshot.Projectiles, projectile.Position)
+	}
+
+	return snapshot
+}
+
+func (w *World) Draw(screen *ebiten.Image, scrollX, scrollY int) {
+	screen.DrawImage(assets.Assets.Images["background"].Image[0], nil)
+
+	w.Clouds.Draw(screen, scrollX, scrollY)
+	w.TileMap.DrawBackground(screen, scrollX, scrollY, "game")
+
+	for _, spawner := range w.Spawners {
+		if spawner.Variant == SpawnerCheckpoint {
+			options := &ebiten.DrawImageOptions{}
+			options.GeoM.Translate(spawner.Position.X-float64(scrollX), spawner.Position.Y-float64(scrollY))
+			screen.DrawImage(assets.Assets.Images["spawners"].Image[SpawnerCheckpoint], options)
+		}
+	}
+
+	w.Player.Draw(screen, scrollX, scrollY)
+
+	for _, enemy := range w.Enemies {
+		enemy.Draw(screen, scrollX, scrollY)
+	}
+
+	w.DashParticles.Draw(screen, scrollX, scrollY)
+	w.Projectiles.Draw(screen, scrollX, scrollY)
+	w.Sparks.Draw(screen, scrollX, scrollY)
+	w.Leafs.Draw(screen, scrollX, scrollY)
+
+	w.TileMap.DrawForeground(screen, scrollX, scrollY, "game")
+}
+
+func entitySnapshot(position, velocity types.Vector, action string, flipped bool) EntitySnapshot {
+	return EntitySnapshot{
+		Position: position,
+		Velocity: velocity,
+		Action:   action,
+		Flipped:  flipped,
+	}
+}
@@ -0,0 +1,266 @@
+package world
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yuricorredor/platformer/assets"
+	"github.com/yuricorredor/platformer/entities"
+	"github.com/yuricorredor/platformer/tilemap"
+	"github.com/yuricorredor/platformer/types"
+)
+
+const (
+	floorY      = 10
+	checkpointX = 6
+	enemyX      = 14
+)
+
+func TestMain(m *testing.M) {
+	if err := assets.Load(assets.FS); err != nil {
+		log.Fatal(err)
+	}
+
+	tileTypes, err := tilemap.LoadTileTypes(assets.FS, tilemap.TileTypesPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tilemap.TileTypes = tileTypes
+
+	os.Exit(m.Run())
+}
+
+// testMap is a flat floor with the player spawning on its left, a checkpoint
+// a few tiles to the right and an enemy further away.
+func testMap(t *testing.T) fstest.MapFS {
+	t.Helper()
+
+	tileMap := &tilemap.TileMapType{TileSize: 16, Layers: tilemap.DefaultLayers()}
+	solid := tileMap.Layer("solid")
+	for x := 0; x < 30; x++ {
+		solid.SetTile(tilemap.Tile{Position: types.Vector{X: float64(x), Y: floorY}, Type: "grass", Variant: 1})
+	}
+
+	background := tileMap.Layer("background")
+	background.SetTile(tilemap.Tile{Position: types.Vector{X: 2, Y: floorY - 2}, Type: "spawners", Variant: SpawnerPlayer})
+	background.SetTile(tilemap.Tile{Position: types.Vector{X: checkpointX, Y: floorY - 1}, Type: "spawners", Variant: SpawnerCheckpoint})
+	background.SetTile(tilemap.Tile{Position: types.Vector{X: enemyX, Y: floorY - 1}, Type: "spawners", Variant: SpawnerEnemy})
+
+	data, err := json.Marshal(tileMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fstest.MapFS{"map.json": {Data: data}}
+}
+
+func newTestWorld(t *testing.T) *World {
+	t.Helper()
+
+	w, err := New(testMap(t), "map.json", 1, entities.DefaultPlayerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w
+}
+
+func step(w *World, input entities.InputSource, frames int) {
+	for i := 0; i < frames; i++ {
+		w.Step(input)
+	}
+}
+
+func TestPlayerLands(t *testing.T) {
+	w := newTestWorld(t)
+
+	step(w, entities.Input{}, 60)
+
+	if bottom := w.Player.Position.Y + w.Player.Height; bottom != floorY*16 {
+		t.Errorf("player bottom = %v, want %v", bottom, floorY*16)
+	}
+	if w.Player.AirTime > 1 {
+		t.Errorf("player has been in the air for %d frames", w.Player.AirTime)
+	}
+	if w.Player.Velocity.Y > w.Player.Config.Gravity {
+		t.Errorf("player velocity = %v, want no vertical speed", w.Player.Velocity)
+	}
+}
+
+// dashIntoEnemy walks the player to the first enemy and dashes through it.
+func dashIntoEnemy(w *World) {
+	for frame := 0; frame < 600 && len(w.Enemies) > 0; frame++ {
+		enemy := w.Enemies[0]
+		input := entities.Input{
+			Right: enemy.Position.X > w.Player.Position.X,
+			Left:  enemy.Position.X < w.Player.Position.X,
+		}
+		distance := enemy.Position.X - w.Player.Position.X
+		if (distance > 0 && distance < 40 && !w.Player.Flipped) || (distance < 0 && distance > -40 && w.Player.Flipped) {
+			input.Dash = true
+		}
+
+		w.Step(input)
+	}
+}
+
+func TestDashKillsEnemy(t *testing.T) {
+	w := newTestWorld(t)
+
+	dashIntoEnemy(w)
+
+	if len(w.Enemies) != 0 {
+		t.Fatalf("enemy still alive at %v, player at %v", w.Enemies[0].Position, w.Player.Position)
+	}
+	if !w.Cleared() {
+		t.Error("level is not cleared")
+	}
+
+	step(w, entities.Input{}, ClearFrames+HitStopFrames)
+	if !w.Finished() {
+		t.Error("level is not finished after the clear delay")
+	}
+}
+
+func TestRespawnAtCheckpoint(t *testing.T) {
+	w := newTestWorld(t)
+	start := w.Checkpoint
+	checkpoint := types.Vector{X: checkpointX * 16, Y: (floorY - 1) * 16}
+
+	step(w, entities.Input{}, 30)
+	step(w, entities.Input{Right: true}, 80)
+
+	if w.Checkpoint != checkpoint {
+		t.Fatalf("checkpoint = %v, want %v (started at %v)", w.Checkpoint, checkpoint, start)
+	}
+
+	w.Player.Health = 1
+	w.Player.Hurt = 0
+	w.Player.Damage(1, &w.Scene)
+	if !w.Player.Dead {
+		t.Fatal("player survived a deadly hit")
+	}
+
+	for frame := 0; frame <= RespawnFrames && w.Player.Dead; frame++ {
+		w.Step(entities.Input{})
+	}
+
+	if w.Player.Dead {
+		t.Fatal("player did not respawn")
+	}
+	if w.Player.Position != checkpoint {
+		t.Errorf("player respawned at %v, want %v", w.Player.Position, checkpoint)
+	}
+	if w.Player.Health != w.Player.Config.MaxHealth {
+		t.Errorf("health = %d, want %d", w.Player.Health, w.Player.Config.MaxHealth)
+	}
+}
+
+func TestSpikesHurt(t *testing.T) {
+	w := newTestWorld(t)
+	w.TileMap.Layer("solid").SetTile(tilemap.Tile{Position: types.Vector{X: 4, Y: floorY - 1}, Type: "spikes"})
+
+	step(w, entities.Input{}, 30)
+	if w.Player.Health != w.Player.Config.MaxHealth {
+		t.Fatalf("health = %d before reaching the spikes", w.Player.Health)
+	}
+
+	for frame := 0; frame < 60 && w.Player.Health == w.Player.Config.MaxHealth; frame++ {
+		w.Step(entities.Input{Right: true})
+	}
+
+	if want := w.Player.Config.MaxHealth - 1; w.Player.Health != want {
+		t.Errorf("health = %d, want %d", w.Player.Health, want)
+	}
+}
+
+func TestShippedMapsLoad(t *testing.T) {
+	paths, err := fs.Glob(assets.FS, "maps/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		if _, err := New(assets.FS, path, 1, entities.DefaultPlayerConfig()); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestWalkLeftOnIce(t *testing.T) {
+	w := newTestWorld(t)
+	for x := 0; x < 30; x++ {
+		w.TileMap.Layer("solid").SetTile(tilemap.Tile{Position: types.Vector{X: float64(x), Y: floorY}, Type: "ice"})
+	}
+
+	step(w, entities.Input{}, 30)
+	if w.Player.Ground.Friction >= 1 {
+		t.Fatalf("player is not on ice, ground = %+v", w.Player.Ground)
+	}
+
+	start := w.Player.Position.X
+	step(w, entities.Input{Left: true}, 10)
+
+	if !w.Player.Flipped {
+		t.Error("player did not turn left")
+	}
+	if w.Player.Action != "run" {
+		t.Errorf("action = %q, want run", w.Player.Action)
+	}
+	if w.Player.Position.X >= start {
+		t.Errorf("player did not slide left, x = %v, started at %v", w.Player.Position.X, start)
+	}
+}
+
+func TestCheckAssets(t *testing.T) {
+	if err := CheckAssets(); err != nil {
+		t.Fatalf("shipped assets: %v", err)
+	}
+
+	shipped := assets.Assets
+	defer func() { assets.Assets = shipped }()
+
+	for _, missing := range []string{"clouds", "background", "spawners", "player"} {
+		broken := *shipped
+		broken.Images = map[string]assets.Asset{}
+		for name, asset := range shipped.Images {
+			if name != missing {
+				broken.Images[name] = asset
+			}
+		}
+		assets.Assets = &broken
+
+		if err := CheckAssets(); err == nil {
+			t.Errorf("no error without %q", missing)
+		}
+	}
+
+	broken := *shipped
+	broken.Sheets = map[string]*assets.SpriteSheet{}
+	assets.Assets = &broken
+	if err := CheckAssets(); err == nil {
+		t.Error("no error without the player sheet")
+	}
+}
+
+func TestJumpPressedDuringHitStop(t *testing.T) {
+	w := newTestWorld(t)
+
+	dashIntoEnemy(w)
+	if w.HitStop == 0 {
+		t.Fatal("killing the enemy did not start a hit-stop")
+	}
+	w.Step(entities.Input{Jump: true})
+	for w.HitStop > 0 {
+		w.Step(entities.Input{HoldJump: true})
+	}
+	w.Step(entities.Input{HoldJump: true})
+
+	if w.Player.Velocity.Y >= 0 {
+		t.Errorf("player did not jump, velocity = %v", w.Player.Velocity)
+	}
+}